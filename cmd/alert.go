@@ -10,8 +10,7 @@ import (
 )
 
 var (
-	alertPrometheusAddress string
-	alertSettingPrefix     string
+	alertSettingPrefix string
 )
 
 // alertCmd represents the alert command
@@ -32,7 +31,7 @@ var alertCmd = &cobra.Command{
 			filters = append(filters, r)
 		}
 
-		err := v1.RunAlert(cfg, filters, alertPrometheusAddress, alertSettingPrefix)
+		err := v1.RunAlert(cfg, filters, alertSettingPrefix)
 		if err != nil {
 			fmt.Fprintln(cmd.ErrOrStderr(), err)
 			os.Exit(1)
@@ -41,7 +40,6 @@ var alertCmd = &cobra.Command{
 }
 
 func init() {
-	alertCmd.Flags().StringVar(&alertPrometheusAddress, "prometheus.address", "http://localhost:9090", "Address of Prometheus")
 	alertCmd.Flags().StringVar(&alertSettingPrefix, "setting.prefix", "ab_", "Prefix to identify a setting from annotations of an alert")
 
 	rootCmd.AddCommand(alertCmd)
@@ -9,17 +9,14 @@ import (
 )
 
 var (
-	drilldownCounterChangeFunc string
-	drilldownGroupLevel        int
-	drilldownSelectors         []string
-	drilldownPrefix            string
-	drilldownTimeRange         string
+	drilldownOpts           drilldownOptions
+	drilldownRowPerEndpoint bool
 )
 
 // drilldownCmd represents the drilldown command
 var drilldownCmd = &cobra.Command{
 	Args:  cobra.MinimumNArgs(2),
-	Use:   "drilldown NAME ENDPOINT",
+	Use:   "drilldown NAME ENDPOINT [ENDPOINT...]",
 	Short: "Create a dashbaord that displays all metrics exposed by a service",
 	Long: `Create a dashbaord that displays all metrics exposed by a service
 
@@ -27,8 +24,25 @@ Arguments:
 NAME: The name of the dashboard in Grafana.
 
 ENDPOINT: The endpoint at which a service exposes its Prometheus metrics, e.g. "http://localhost:9090/metrics".
+  ENDPOINT can also be a file that contains metrics, either as a path or as a file:// URL, or "-" to read metrics from
+  stdin. Metrics can be in the Prometheus text format or in OpenMetrics.
+  If --source is "prometheus", ENDPOINT is a series selector, e.g. '{job="node"}', that selects the metrics of a
+  service in Prometheus.
+  Set more than one ENDPOINT to merge the metrics of all endpoints into one dashboard, e.g. the metrics of a service
+  and of its sidecar.
 
 Flags:
+--cardinality-limit, --topk: Panels of counters and gauges display every series of a metric. If a metric has more
+  series than --cardinality-limit, e.g. because it has a "pod" label, autoboard aggregates the series by the labels with
+  the fewest values, e.g. "sum by (code) (...)". If every label has too many values, it displays the top --topk series.
+  --cardinality-limit is 0 by default, which disables the aggregation.
+
+--collapse-rows, --collapse-rows-above: Dashboards with many rows load slowly in Grafana. Collapsed rows load their
+  panels only when they are expanded. --collapse-rows collapses all rows except the row "General".
+  --collapse-rows-above collapses rows that contain more panels than its value.
+
+--combine-quantiles: Display all quantiles of a histogram in one panel instead of one panel per quantile.
+
 --counter-func: autoboard converts counters into panels that display the change of the metric. This flag allows changing
   which PromQL function to use.
 
@@ -43,13 +57,81 @@ Flags:
 	Example: go_memstats_alloc_bytes will be put under the row "go_memstats" if group-level is set to 2.
 	Setting the value to 0 (the default) disables grouping.
 
+--heatmap: autoboard can display the buckets of a histogram as a heatmap. "none" (the default) does not create a
+  heatmap. "add" adds the heatmap to the graphs of the average and the quantiles. "replace" replaces the graphs of the
+  quantiles with the heatmap.
+
+--histogram-by: autoboard aggregates the buckets of a histogram by "le" and all other labels of the histogram, e.g.
+  "sum by (le, handler) (...)". Set --histogram-by to aggregate by some labels only. Can be set multiple times.
+
+--preset, --disable-preset: autoboard recognizes the metrics of well-known exporters and runtimes and replaces their
+  generic panels with a row of hand-tuned panels. No preset is enabled by default. Set --preset to enable a preset or
+  --preset=all to enable all of them. Set --disable-preset to disable single presets enabled via --preset=all.
+  Available presets: go, process, node, redis, jvm, envoy.
+
+--quantile: The quantiles to display for histograms, e.g. --quantile=0.5,0.95,0.999.
+
+--red, --red-by: autoboard detects a counter of requests, e.g. "http_requests_total" with a "code" label, and a
+  histogram of the duration of requests, e.g. "http_request_duration_seconds". It replaces their generic panels with a
+  RED row that displays the rate of requests, the ratio of errors and percentiles of the duration. The panels aggregate
+  by the label set via --red-by or, if it is empty, by one of "handler", "route", "path", "method" or "grpc_method".
+  The detection is disabled by default. Set --red to enable it.
+
+--repeat-by: Put metrics that have the label, e.g. "handler", in rows that Grafana repeats for every value of the label
+  selected in the variable of the label. The panels of a repeated row display the series of one value only.
+  The label cannot also be set via --selector.
+
+--row-per-endpoint: Put the metrics of each ENDPOINT in their own row instead of merging them. Takes precedence over
+  --group-level. Has no effect if only one ENDPOINT is set.
+
 --selector: Selectors are added to the dashbaord as variables. They allow switching between different instances of
-  services. This flag can be set multiple times to set multiple selectors.
+  services. This flag can be set multiple times to set multiple selectors. Each variable only offers the values of the
+  series that match the variables before it, e.g. the instances of the selected jobs. A variable allows to select
+  multiple values or all values.
+
+--source: Either "endpoint" (the default) or "prometheus". If set to "prometheus", autoboard does not read metrics from
+  the service itself. It reads them from Prometheus instead, using the metadata API for types and help texts and the
+  series API for label keys. Set --prometheus.address to configure the address of Prometheus.
+
+--use: autoboard detects metrics that describe the used part and the capacity of a resource, e.g. "process_open_fds"
+  and "process_max_fds" or "queue_length" and "queue_capacity". It replaces their generic panels with a USE row that
+  displays the utilization of the resource, gauges of pending work and counters of errors of the resource.
+  The detection is disabled by default. Set --use to enable it.
+
+--drilldown.*: Configure how autoboard connects to ENDPOINT, e.g. to authenticate via a bearer token or a client
+  certificate. The settings mirror the HTTP settings of a scrape_config in Prometheus and can also be set in the config
+  file under the key "drilldown". Custom headers can only be set in the config file.
 
 `,
 	Run: func(cmd *cobra.Command, args []string) {
-		d := v1.NewDrilldown()
-		err := d.Run(cfg, drilldownCounterChangeFunc, args[1], drilldownGroupLevel, drilldownSelectors, args[0], drilldownPrefix, drilldownTimeRange)
+		source := &v1.MultiSource{RowPerSource: drilldownRowPerEndpoint}
+		for _, endpoint := range args[1:] {
+			var s v1.MetricSource
+			var err error
+			switch drilldownOpts.source {
+			case "endpoint":
+				s, err = v1.NewEndpointSource(cfg, endpoint)
+			case "prometheus":
+				s, err = v1.NewMetadataSource(cfg, endpoint)
+			default:
+				err = fmt.Errorf("unknown source %s", drilldownOpts.source)
+			}
+
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+
+			source.Sources = append(source.Sources, s)
+		}
+
+		d, err := drilldownOpts.newDrilldown()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		err = d.Run(cfg, drilldownOpts.counterChangeFunc, source, drilldownOpts.groupLevel, drilldownOpts.selectors, args[0], drilldownOpts.prefix, drilldownOpts.timeRange)
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
@@ -58,10 +140,7 @@ Flags:
 }
 
 func init() {
-	drilldownCmd.Flags().StringVar(&drilldownCounterChangeFunc, "counter-func", "rate", "PromQL function to use in panels that display the change of a counter")
-	drilldownCmd.Flags().StringVar(&drilldownTimeRange, "counter-range", "5m", "PromQL range duration to use in panels that display the change of a counter")
-	drilldownCmd.Flags().StringVar(&drilldownPrefix, "filter", "", "Filter metrics for which to create panels by their prefix")
-	drilldownCmd.Flags().IntVar(&drilldownGroupLevel, "group-level", 0, "Group related metrics in rows")
-	drilldownCmd.Flags().StringArrayVar(&drilldownSelectors, "selector", []string{"instance"}, "Add dropdowns to the dashbaord.")
+	addDrilldownFlags(drilldownCmd, &drilldownOpts, []string{"instance"})
+	drilldownCmd.Flags().BoolVar(&drilldownRowPerEndpoint, "row-per-endpoint", false, "Put the metrics of each endpoint in their own row")
 	rootCmd.AddCommand(drilldownCmd)
 }
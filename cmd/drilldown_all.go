@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	drilldownAllOpts        drilldownOptions
+	drilldownAllTitlePrefix string
+)
+
+// drilldownAllCmd represents the drilldown-all command
+var drilldownAllCmd = &cobra.Command{
+	Use:   "drilldown-all [JOB...]",
+	Short: "Create a drilldown dashboard for every job scraped by Prometheus",
+	Long: `Create a drilldown dashboard for every job scraped by Prometheus
+
+autoboard reads the active targets from Prometheus, groups them by their "job" label and creates one dashboard per job.
+The variables of each dashboard only offer values of that job.
+
+Arguments:
+JOB: Regular expressions to select the jobs for which to create dashboards. All jobs are selected if no JOB is set.
+
+Flags:
+--source: Either "endpoint" (the default) or "prometheus". If set to "endpoint", autoboard reads the metrics of a job
+  from the scrape URL of one healthy target of that job. If set to "prometheus", autoboard reads them from the
+  metadata API of Prometheus.
+
+--drilldown.*: Configure how autoboard connects to the scrape URL of a target if --source is "endpoint", e.g. to
+  authenticate via a bearer token or a client certificate.
+
+--title-prefix: Prefix to prepend to the name of a job to form the title of its dashboard.
+
+All other flags work like the flags of the "drilldown" command.
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		filters := []*regexp.Regexp{}
+		for _, a := range args {
+			r, err := regexp.Compile(a)
+			if err != nil {
+				fmt.Printf("Create regex from %s: %s\n", a, err)
+				os.Exit(1)
+			}
+
+			filters = append(filters, r)
+		}
+
+		var useMetadata bool
+		switch drilldownAllOpts.source {
+		case "endpoint":
+			useMetadata = false
+		case "prometheus":
+			useMetadata = true
+		default:
+			fmt.Printf("unknown source %s\n", drilldownAllOpts.source)
+			os.Exit(1)
+		}
+
+		d, err := drilldownAllOpts.newDrilldown()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		err = d.RunAll(cfg, drilldownAllOpts.counterChangeFunc, useMetadata, filters, drilldownAllOpts.groupLevel, drilldownAllOpts.selectors, drilldownAllTitlePrefix, drilldownAllOpts.prefix, drilldownAllOpts.timeRange)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	addDrilldownFlags(drilldownAllCmd, &drilldownAllOpts, []string{"job", "instance"})
+	drilldownAllCmd.Flags().StringVar(&drilldownAllTitlePrefix, "title-prefix", "", "Prefix of the title of each dashboard")
+	rootCmd.AddCommand(drilldownAllCmd)
+}
@@ -0,0 +1,15 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrilldownAllCmd_HTTPClientFlags(t *testing.T) {
+	err := drilldownAllCmd.ParseFlags([]string{"--drilldown.bearer_token_file=/etc/autoboard/token", "--drilldown.scrape_timeout=30s"})
+	require.NoError(t, err)
+	require.Equal(t, "/etc/autoboard/token", viper.GetString("drilldown.bearer_token_file"))
+	require.Equal(t, "30s", viper.GetDuration("drilldown.scrape_timeout").String())
+}
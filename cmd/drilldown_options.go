@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+	v1 "github.com/wndhydrnt/autoboard/pkg"
+)
+
+// drilldownHTTPFlags holds the flags that configure how the drilldown commands connect to an endpoint.
+// Viper binds a key to only one flag, so both commands add the same flags instead of each creating its own.
+var drilldownHTTPFlags = newDrilldownHTTPFlags()
+
+func newDrilldownHTTPFlags() *cobra.Command {
+	cmd := &cobra.Command{}
+	addFlagDuration(cmd, "drilldown.scrape_timeout", 5*time.Second, "Timeout when reading metrics from an endpoint")
+	addHTTPClientFlags(cmd, "drilldown", "an endpoint")
+	return cmd
+}
+
+// drilldownOptions holds the flags that the commands drilldown and drilldown-all share.
+type drilldownOptions struct {
+	cardinalityLimit  int
+	collapseRows      bool
+	collapseRowsAbove int
+	combineQuantiles  bool
+	counterChangeFunc string
+	disabledPresets   []string
+	groupLevel        int
+	heatmap           string
+	histogramBy       []string
+	selectors         []string
+	prefix            string
+	presets           []string
+	quantiles         []float64
+	red               bool
+	redBy             string
+	repeatBy          string
+	source            string
+	timeRange         string
+	topK              int
+	use               bool
+}
+
+// addDrilldownFlags registers the shared flags of a drilldown command and binds them to o.
+// selectors is the default value of the flag --selector.
+func addDrilldownFlags(cmd *cobra.Command, o *drilldownOptions, selectors []string) {
+	cmd.Flags().IntVar(&o.cardinalityLimit, "cardinality-limit", 0, "Number of series of a metric above which queries aggregate the series. 0 disables aggregation")
+	cmd.Flags().BoolVar(&o.collapseRows, "collapse-rows", false, "Collapse all rows except the row General")
+	cmd.Flags().IntVar(&o.collapseRowsAbove, "collapse-rows-above", 0, "Collapse rows that contain more than this number of panels. 0 disables collapsing")
+	cmd.Flags().BoolVar(&o.combineQuantiles, "combine-quantiles", false, "Display all quantiles of a histogram in one panel")
+	cmd.Flags().StringVar(&o.counterChangeFunc, "counter-func", "rate", "PromQL function to use in panels that display the change of a counter")
+	cmd.Flags().StringVar(&o.timeRange, "counter-range", "5m", "PromQL range duration to use in panels that display the change of a counter")
+	cmd.Flags().StringArrayVar(&o.disabledPresets, "disable-preset", []string{}, "Disable a preset. Can be set multiple times")
+	cmd.Flags().StringVar(&o.prefix, "filter", "", "Filter metrics for which to create panels by their prefix")
+	cmd.Flags().IntVar(&o.groupLevel, "group-level", 0, "Group related metrics in rows")
+	cmd.Flags().StringVar(&o.heatmap, "heatmap", v1.HeatmapModeNone, "Display histograms as a heatmap, either none, add or replace")
+	cmd.Flags().StringArrayVar(&o.histogramBy, "histogram-by", []string{}, "Aggregate the buckets of histograms by a label. Can be set multiple times")
+	cmd.Flags().StringArrayVar(&o.presets, "preset", []string{}, "Replace the panels of well-known metrics with hand-tuned panels, or all to enable all presets. Can be set multiple times")
+	cmd.Flags().Float64SliceVar(&o.quantiles, "quantile", v1.DefaultQuantiles, "Quantiles to display for histograms")
+	cmd.Flags().BoolVar(&o.red, "red", false, "Create a row of rate, errors and duration for metrics of requests")
+	cmd.Flags().StringVar(&o.redBy, "red-by", "", "Label by which the panels of a RED row aggregate, e.g. handler")
+	cmd.Flags().StringVar(&o.repeatBy, "repeat-by", "", "Repeat a row for every value of a label, e.g. handler, for metrics that have the label")
+	cmd.Flags().StringVar(&o.source, "source", "endpoint", "Where to read metrics from, either endpoint or prometheus")
+	cmd.Flags().StringArrayVar(&o.selectors, "selector", selectors, "Add dropdowns to the dashbaord.")
+	cmd.Flags().IntVar(&o.topK, "topk", 10, "Number of series to display if aggregating by labels does not stay within --cardinality-limit")
+	cmd.Flags().BoolVar(&o.use, "use", false, "Create a row of utilization, saturation and errors for metrics of resources")
+	cmd.PersistentFlags().AddFlagSet(drilldownHTTPFlags.PersistentFlags())
+}
+
+// newDrilldown returns a Drilldown configured by the options.
+func (o *drilldownOptions) newDrilldown() (*v1.Drilldown, error) {
+	presets, err := v1.SelectPresets(o.presets, o.disabledPresets)
+	if err != nil {
+		return nil, err
+	}
+
+	d := v1.NewDrilldown()
+	d.CardinalityLimit = o.cardinalityLimit
+	d.CollapseRows = o.collapseRows
+	d.CollapseRowsAbove = o.collapseRowsAbove
+	d.CombineQuantiles = o.combineQuantiles
+	d.Heatmap = o.heatmap
+	d.HistogramBy = o.histogramBy
+	d.Presets = presets
+	d.Quantiles = o.quantiles
+	d.RED = o.red
+	d.REDBy = o.redBy
+	d.RepeatBy = o.repeatBy
+	d.TopK = o.topK
+	d.USE = o.use
+	return d, nil
+}
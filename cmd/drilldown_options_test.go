@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	v1 "github.com/wndhydrnt/autoboard/pkg"
+)
+
+func TestDrilldownOptions_DefaultsMatchLibrary(t *testing.T) {
+	d, err := drilldownOpts.newDrilldown()
+	require.NoError(t, err)
+
+	expected := v1.NewDrilldown()
+	// Values that differ only in how they express the default.
+	expected.Heatmap = v1.HeatmapModeNone
+	expected.HistogramBy = []string{}
+	expected.Presets = []v1.Preset{}
+	expected.Quantiles = v1.DefaultQuantiles
+	require.Equal(t, expected, d)
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -33,16 +34,30 @@ func init() {
 	addFlagString(rootCmd, "grafana.address", "http://localhost:3000", "Address of Grafana")
 	addFlagString(rootCmd, "grafana.datasource", "", "Datasource to set in queries in Grafana")
 	addFlagString(rootCmd, "grafana.folder", "", "Name of the folder in which to create the dashboard")
+	addFlagString(rootCmd, "grafana.loki.datasource", "", "Datasource to set in queries of alerts written in LogQL")
 	addFlagInt(rootCmd, "grafana.panels.height", 5, "Height of a panel on a dashboard")
 	addFlagInt(rootCmd, "grafana.panels.graph.width", 12, "Width of a Graph panel on a dashboard")
+	addFlagInt(rootCmd, "grafana.panels.grid.columns", 3, "Number of columns of the grid layout")
+	addFlagString(rootCmd, "grafana.panels.layout", config.LayoutFlow, "How to place panels on a dashboard: flow, fill, grid or compact")
 	addFlagInt(rootCmd, "grafana.panels.singlestat.width", 6, "Width of a Singlestat panel on a dashboard")
+	addFlagString(rootCmd, "grafana.panels.style", config.PanelStyleLegacy, "Either legacy to create graph and singlestat panels or modern to create timeseries and stat panels")
 	addFlagString(rootCmd, "grafana.password", "", "Password to authenticate at the Grafana API")
 	addFlagString(rootCmd, "grafana.username", "", "Username to authenticate at the Grafana API")
 	addFlagString(rootCmd, "log.level", "error", "Log level")
+	addFlagString(rootCmd, "prometheus.address", "http://localhost:9090", "Address of Prometheus")
+	addFlagString(rootCmd, "prometheus.api", "prometheus", "API from which to read rules, one of prometheus, thanos, cortex, mimir or loki")
+	addFlagString(rootCmd, "prometheus.tenant", "", "Tenant to send in the X-Scope-OrgID header, required by Cortex, Mimir and Loki")
+	addHTTPClientFlags(rootCmd, "prometheus", "the Prometheus API")
+	addFlagString(rootCmd, "templates.bargauge", "", "Path to the template used to render a bar gauge")
 	addFlagString(rootCmd, "templates.dashboard", "", "Path to the template used to render a dashboard")
+	addFlagString(rootCmd, "templates.gauge", "", "Path to the template used to render a gauge")
 	addFlagString(rootCmd, "templates.graph", "", "Path to the template used to render a graph")
+	addFlagString(rootCmd, "templates.heatmap", "", "Path to the template used to render a heatmap")
 	addFlagString(rootCmd, "templates.row", "", "Path to the template used to render a row")
 	addFlagString(rootCmd, "templates.singlestat", "", "Path to the template used to render a singlestat")
+	addFlagString(rootCmd, "templates.stat", "", "Path to the template used to render a stat")
+	addFlagString(rootCmd, "templates.table", "", "Path to the template used to render a table")
+	addFlagString(rootCmd, "templates.timeseries", "", "Path to the template used to render a time series")
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -55,6 +70,31 @@ func initConfig() {
 	}
 }
 
+// addHTTPClientFlags adds flags for all settings of config.HTTPClientConfig except headers.
+func addHTTPClientFlags(cmd *cobra.Command, prefix, target string) {
+	addFlagString(cmd, prefix+".basic_auth.password", "", "Password to authenticate at "+target)
+	addFlagString(cmd, prefix+".basic_auth.password_file", "", "File to read the password to authenticate at "+target+" from")
+	addFlagString(cmd, prefix+".basic_auth.username", "", "Username to authenticate at "+target)
+	addFlagString(cmd, prefix+".bearer_token", "", "Bearer token to authenticate at "+target)
+	addFlagString(cmd, prefix+".bearer_token_file", "", "File to read the bearer token to authenticate at "+target+" from")
+	addFlagString(cmd, prefix+".proxy_url", "", "URL of the HTTP proxy to use to connect to "+target)
+	addFlagString(cmd, prefix+".tls_config.ca_file", "", "CA certificate to validate the certificate of "+target)
+	addFlagString(cmd, prefix+".tls_config.cert_file", "", "Client certificate to authenticate at "+target)
+	addFlagBool(cmd, prefix+".tls_config.insecure_skip_verify", false, "Disable validation of the certificate of "+target)
+	addFlagString(cmd, prefix+".tls_config.key_file", "", "Key of the client certificate to authenticate at "+target)
+	addFlagString(cmd, prefix+".tls_config.server_name", "", "Server name used to verify the certificate of "+target)
+}
+
+func addFlagBool(cmd *cobra.Command, name string, value bool, usage string) {
+	cmd.PersistentFlags().Bool(name, value, usage)
+	viper.BindPFlag(name, cmd.PersistentFlags().Lookup(name))
+}
+
+func addFlagDuration(cmd *cobra.Command, name string, value time.Duration, usage string) {
+	cmd.PersistentFlags().Duration(name, value, usage)
+	viper.BindPFlag(name, cmd.PersistentFlags().Lookup(name))
+}
+
 func addFlagInt(cmd *cobra.Command, name string, value int, usage string) {
 	cmd.PersistentFlags().Int(name, value, usage)
 	viper.BindPFlag(name, cmd.PersistentFlags().Lookup(name))
@@ -3,6 +3,7 @@ package v1
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 
 	pav1 "github.com/prometheus/client_golang/api/prometheus/v1"
@@ -37,7 +38,7 @@ func ConvertAlertToPanel(alert pav1.AlertingRule, datasource string) (r interfac
 		ss := Singlestat{
 			Datasource:         datasource,
 			Format:             format,
-			Query:              escapeQuery(be.LHS.String()),
+			Query:              be.LHS.String(),
 			ThresholdInvertNo:  be.Op == parser.GTR || be.Op == parser.GTE,
 			ThresholdInvertYes: be.Op == parser.LSS || be.Op == parser.LTE,
 			ThresholdValue:     be.RHS.String(),
@@ -56,7 +57,7 @@ func ConvertAlertToPanel(alert pav1.AlertingRule, datasource string) (r interfac
 		ss := Singlestat{
 			Datasource:         datasource,
 			Format:             format,
-			Query:              escapeQuery(be.RHS.String()),
+			Query:              be.RHS.String(),
 			ThresholdInvertNo:  be.Op == parser.LSS || be.Op == parser.LTE,
 			ThresholdInvertYes: be.Op == parser.GTR || be.Op == parser.GTE,
 			ThresholdValue:     be.LHS.String(),
@@ -71,63 +72,166 @@ func ConvertAlertToPanel(alert pav1.AlertingRule, datasource string) (r interfac
 		Legend:     strings.ReplaceAll(strings.ReplaceAll(settingString(alert, "legend", ""), "[[", "{{"), "]]", "}}"),
 	}
 	g.HasLegend = g.Legend != ""
-	gq := []GraphQuery{}
-	if be.LHS.Type() == parser.ValueTypeScalar {
-		if be.Op == parser.LSS || be.Op == parser.LTE {
-			g.HasThreshold = true
-			g.ThresholdOP = "lt"
-			g.ThresholdValue = be.LHS.String()
+	lhsIsNumber := be.LHS.Type() == parser.ValueTypeScalar
+	rhsIsNumber := be.RHS.Type() == parser.ValueTypeScalar
+	return setComparison(g, be.LHS.String(), lhsIsNumber, be.Op.String(), be.RHS.String(), rhsIsNumber), nil
+}
+
+// ConvertLogQLAlertToPanel takes a Loki Alerting Rule as input and converts it to a Graph panel.
+// The PromQL parser cannot parse LogQL. The query is split at its top-level comparison operator instead.
+// A threshold is set on the Graph panel if one side of the comparison is a number.
+func ConvertLogQLAlertToPanel(alert pav1.AlertingRule, datasource string) (interface{}, error) {
+	lhs, op, rhs := splitComparison(alert.Query)
+	g := Graph{
+		Datasource: datasource,
+		Format:     settingString(alert, "format", defaultFormat),
+		Legend:     strings.ReplaceAll(strings.ReplaceAll(settingString(alert, "legend", ""), "[[", "{{"), "]]", "}}"),
+	}
+	g.HasLegend = g.Legend != ""
+	if op == "" {
+		g.Queries = []GraphQuery{{Query: alert.Query}}
+		return g, nil
+	}
+
+	return setComparison(g, lhs, isNumber(lhs), op, rhs, isNumber(rhs)), nil
+}
+
+// setComparison sets the queries and the threshold of a Graph from the sides of a comparison.
+// A side that is a number becomes the value of the threshold. All other sides become queries.
+func setComparison(g Graph, lhs string, lhsIsNumber bool, op string, rhs string, rhsIsNumber bool) Graph {
+	g.ThresholdOP = thresholdOP(lhsIsNumber, op, rhsIsNumber)
+	g.HasThreshold = g.ThresholdOP != ""
+	g.Queries = []GraphQuery{}
+	if lhsIsNumber {
+		if g.HasThreshold {
+			g.ThresholdValue = lhs
 		}
+	} else {
+		g.Queries = append(g.Queries, GraphQuery{Query: lhs})
+	}
 
-		if be.Op == parser.GTR || be.Op == parser.GTE {
-			g.HasThreshold = true
-			g.ThresholdOP = "gt"
-			g.ThresholdValue = be.LHS.String()
+	if rhsIsNumber {
+		if g.HasThreshold {
+			g.ThresholdValue = rhs
 		}
 	} else {
-		gq = append(gq, GraphQuery{Query: escapeQuery(be.LHS.String())})
+		g.Queries = append(g.Queries, GraphQuery{Query: rhs})
 	}
 
-	if be.RHS.Type() == parser.ValueTypeScalar {
-		if be.Op == parser.LSS || be.Op == parser.LTE {
-			g.HasThreshold = true
-			g.ThresholdOP = "lt"
-			g.ThresholdValue = be.RHS.String()
+	return g
+}
+
+// thresholdOP returns the operator of the threshold of a Graph, "gt" or "lt", for a comparison with a number.
+// The operator is flipped if the number is on the left side, e.g. "5 > x" is the threshold "lt" of 5.
+// It returns an empty string if no side is a number or op is not a comparison of greater or less.
+func thresholdOP(lhsIsNumber bool, op string, rhsIsNumber bool) string {
+	if lhsIsNumber == rhsIsNumber {
+		return ""
+	}
+
+	switch op {
+	case ">", ">=":
+		if lhsIsNumber {
+			return "lt"
 		}
 
-		if be.Op == parser.GTR || be.Op == parser.GTE {
-			g.HasThreshold = true
-			g.ThresholdOP = "gt"
-			g.ThresholdValue = be.RHS.String()
+		return "gt"
+	case "<", "<=":
+		if lhsIsNumber {
+			return "gt"
 		}
-	} else {
-		gq = append(gq, GraphQuery{Query: escapeQuery(be.RHS.String())})
 
-		if len(gq) == 2 {
-			gq[0].HasMore = true
+		return "lt"
+	default:
+		return ""
+	}
+}
+
+// isLogQL reports whether a query is written in LogQL.
+// A query that parses as PromQL is PromQL. A query that does not parse is considered LogQL if it contains a log
+// pipeline. Otherwise it is treated as PromQL so that the error of the parser is reported.
+func isLogQL(q string) bool {
+	_, err := parser.ParseExpr(q)
+	if err == nil {
+		return false
+	}
+
+	return logPipelineRegex.MatchString(q)
+}
+
+var logPipelineRegex = regexp.MustCompile(`}\s*(\|=|\|~|!=|!~|\|\s*(json|logfmt|regexp|pattern|unpack|line_format|label_format))`)
+
+// splitComparison splits a query at its first comparison operator that is not nested in brackets or strings.
+// Only the operators ">", ">=", "<" and "<=" split a query because Grafana has no threshold for "==" and "!=".
+// It returns the query unchanged and an empty operator if it does not contain such a comparison.
+func splitComparison(q string) (lhs, op, rhs string) {
+	depth := 0
+	var quote rune
+	escaped := false
+	for i, c := range q {
+		if quote != 0 {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\' && quote != '`':
+				escaped = true
+			case c == quote:
+				quote = 0
+			}
+
+			continue
+		}
+
+		switch c {
+		case '"', '\'', '`':
+			quote = c
+		case '(', '{', '[':
+			depth++
+		case ')', '}', ']':
+			depth--
+		case '>', '<':
+			if depth != 0 {
+				continue
+			}
+
+			op = string(c)
+			if i+1 < len(q) && q[i+1] == '=' {
+				op = op + "="
+			}
+
+			rhs = strings.TrimSpace(q[i+len(op):])
+			rhs = strings.TrimSpace(strings.TrimPrefix(rhs, "bool "))
+			return strings.TrimSpace(q[:i]), op, rhs
 		}
 	}
 
-	g.Queries = gq
-	return g, nil
+	return q, "", ""
 }
 
-func escapeQuery(q string) string {
-	return strings.ReplaceAll(q, `"`, `\"`)
+func isNumber(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
 }
 
 // RunAlert is the entrypoint to create a dashboard from an alert.
-func RunAlert(cfg config.Config, filters []*regexp.Regexp, promAddr string, settingPrefix string) error {
+func RunAlert(cfg config.Config, filters []*regexp.Regexp, settingPrefix string) error {
 	SetPrefix(settingPrefix)
 	log.SetLevel(cfg.LogLevel)
-	promapi, err := NewPrometheusAPI(promAddr)
+	rt, err := newRoundTripper(cfg.PrometheusHTTP, cfg.PrometheusTenant)
+	if err != nil {
+		return fmt.Errorf("init Prometheus HTTP client: %w", err)
+	}
+
+	promapi, err := NewRulesAPI(cfg.PrometheusAPI, cfg.PrometheusAddress, rt)
 	if err != nil {
 		return fmt.Errorf("init Prometheus API client: %w", err)
 	}
 
 	p := &Prometheus{
 		DatasourceDefault: cfg.Datasource,
+		DatasourceLoki:    cfg.DatasourceLoki,
 		Filters:           filters,
+		LogQL:             cfg.PrometheusAPI == RulesAPILoki,
 		PromAPI:           promapi,
 	}
 	alerts, err := p.ReadAlerts()
@@ -135,23 +239,19 @@ func RunAlert(cfg config.Config, filters []*regexp.Regexp, promAddr string, sett
 		return fmt.Errorf("read alerts from Prometheus: %w", err)
 	}
 
-	r := &Renderer{
-		dashboardTpl:         cfg.TemplateDashboard,
-		datasource:           cfg.Datasource,
-		graphTpl:             cfg.TemplateGraph,
-		panelHeight:          cfg.GrafanaPanelsHeight,
-		panelWidthGraph:      cfg.GrafanaPanelsGraphWidth,
-		panelWidthSinglestat: cfg.GrafanaPanelsSinglestatWidth,
-		singlestatTpl:        cfg.TemplateSinglestat,
-	}
+	r := newRenderer(cfg)
 	gf := &Grafana{
 		Address:  cfg.GrafanaAddress,
 		Password: cfg.GrafanaPassword,
 		Username: cfg.GrafanaUsername,
 	}
 	for _, a := range alerts {
-		s := r.Render(a.Dashboard, a.Panels)
-		err := gf.CreateDashboard(s, cfg.GrafanaFolder)
+		s, err := r.Render(a.Dashboard, a.Panels)
+		if err != nil {
+			return fmt.Errorf("render board %s: %w", a.Dashboard.Title, err)
+		}
+
+		err = gf.CreateDashboard(s, cfg.GrafanaFolder)
 		if err != nil {
 			return fmt.Errorf("create board %s: %w", a.Dashboard.Title, err)
 		}
@@ -0,0 +1,108 @@
+package v1
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+)
+
+// Labels that do not identify a series of a Metric but one of the values of a histogram or a summary.
+var cardinalityIgnoredLabels = map[string]struct{}{
+	labels.MetricName: {},
+	"le":              {},
+	"quantile":        {},
+}
+
+// cardinalityCounter counts the series of a Metric and the distinct values of each of its labels.
+type cardinalityCounter struct {
+	series map[string]struct{}
+	values map[string]map[string]struct{}
+}
+
+func newCardinalityCounter() *cardinalityCounter {
+	return &cardinalityCounter{
+		series: map[string]struct{}{},
+		values: map[string]map[string]struct{}{},
+	}
+}
+
+// add counts one series. Labels in skip are not counted, e.g. the labels of a target.
+func (c *cardinalityCounter) add(lset labels.Labels, skip map[string]struct{}) {
+	id := []string{}
+	for _, l := range lset {
+		if _, ignored := cardinalityIgnoredLabels[l.Name]; ignored {
+			continue
+		}
+
+		if _, skipped := skip[l.Name]; skipped {
+			continue
+		}
+
+		id = append(id, l.Name+"="+l.Value)
+		if _, exists := c.values[l.Name]; !exists {
+			c.values[l.Name] = map[string]struct{}{}
+		}
+
+		c.values[l.Name][l.Value] = struct{}{}
+	}
+
+	c.series[strings.Join(id, ",")] = struct{}{}
+}
+
+// apply sets the counts on a Metric.
+func (c *cardinalityCounter) apply(m *Metric) {
+	m.Series = len(c.series)
+	if len(c.values) == 0 {
+		return
+	}
+
+	m.LabelValues = map[string]int{}
+	for name, values := range c.values {
+		m.LabelValues[name] = len(values)
+	}
+}
+
+// limitCardinality aggregates a query if the Metric has more series than the limit set in Options.
+// It aggregates by the labels with the lowest number of values as long as the product of their values stays within the
+// limit. If even the label with the lowest number of values exceeds the limit, it selects the top k series instead.
+// It returns the query and the label keys to display in the legend.
+func limitCardinality(m Metric, o Options, query string, legendKeys []string) (string, []string) {
+	if o.CardinalityLimit <= 0 || m.Series <= o.CardinalityLimit {
+		return query, legendKeys
+	}
+
+	candidates := []string{}
+	for _, lk := range legendKeys {
+		if _, ok := m.LabelValues[lk]; ok {
+			candidates = append(candidates, lk)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return m.LabelValues[candidates[i]] < m.LabelValues[candidates[j]]
+	})
+	by := []string{}
+	product := 1
+	for _, lk := range candidates {
+		if product*m.LabelValues[lk] > o.CardinalityLimit {
+			break
+		}
+
+		product = product * m.LabelValues[lk]
+		by = append(by, lk)
+	}
+
+	if len(by) > 0 {
+		sort.Strings(by)
+		return fmt.Sprintf("sum by (%s) (%s)", strings.Join(by, ", "), query), by
+	}
+
+	topK := o.TopK
+	if topK <= 0 {
+		topK = o.CardinalityLimit
+	}
+
+	return fmt.Sprintf("topk(%d, %s)", topK, query), legendKeys
+}
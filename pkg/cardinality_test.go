@@ -0,0 +1,84 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/prometheus/prometheus/pkg/textparse"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMetrics_Cardinality(t *testing.T) {
+	input := `# TYPE http_request_duration_seconds histogram
+http_request_duration_seconds_bucket{handler="/a",pod="a-1",le="0.1"} 1
+http_request_duration_seconds_bucket{handler="/a",pod="a-1",le="+Inf"} 1
+http_request_duration_seconds_sum{handler="/a",pod="a-1"} 0.1
+http_request_duration_seconds_count{handler="/a",pod="a-1"} 1
+http_request_duration_seconds_bucket{handler="/b",pod="a-2",le="0.1"} 1
+http_request_duration_seconds_bucket{handler="/b",pod="a-2",le="+Inf"} 1
+http_request_duration_seconds_sum{handler="/b",pod="a-2"} 0.1
+http_request_duration_seconds_count{handler="/b",pod="a-2"} 1
+http_request_duration_seconds_bucket{handler="/b",pod="a-3",le="0.1"} 1
+http_request_duration_seconds_bucket{handler="/b",pod="a-3",le="+Inf"} 1
+http_request_duration_seconds_sum{handler="/b",pod="a-3"} 0.1
+http_request_duration_seconds_count{handler="/b",pod="a-3"} 1
+`
+	metrics := parseMetrics([]byte(input), contentTypeText)
+	require.Len(t, metrics, 1)
+	require.Equal(t, 3, metrics[0].Series)
+	require.Equal(t, map[string]int{"handler": 2, "pod": 3}, metrics[0].LabelValues)
+}
+
+func TestLimitCardinality(t *testing.T) {
+	m := Metric{
+		LabelKeys:   []string{"code", "handler", "pod"},
+		LabelValues: map[string]int{"code": 3, "handler": 5, "pod": 200},
+		Name:        "http_requests_total",
+		Series:      300,
+		Type:        textparse.MetricTypeCounter,
+	}
+
+	query, legend := limitCardinality(m, Options{}, "rate(http_requests_total[5m])", m.LabelKeys)
+	require.Equal(t, "rate(http_requests_total[5m])", query)
+	require.Equal(t, m.LabelKeys, legend)
+
+	query, legend = limitCardinality(m, Options{CardinalityLimit: 300}, "rate(http_requests_total[5m])", m.LabelKeys)
+	require.Equal(t, "rate(http_requests_total[5m])", query)
+	require.Equal(t, m.LabelKeys, legend)
+
+	query, legend = limitCardinality(m, Options{CardinalityLimit: 20}, "rate(http_requests_total[5m])", m.LabelKeys)
+	require.Equal(t, "sum by (code, handler) (rate(http_requests_total[5m]))", query)
+	require.Equal(t, []string{"code", "handler"}, legend)
+
+	query, legend = limitCardinality(m, Options{CardinalityLimit: 10}, "rate(http_requests_total[5m])", m.LabelKeys)
+	require.Equal(t, "sum by (code) (rate(http_requests_total[5m]))", query)
+	require.Equal(t, []string{"code"}, legend)
+
+	query, legend = limitCardinality(m, Options{CardinalityLimit: 2, TopK: 5}, "rate(http_requests_total[5m])", m.LabelKeys)
+	require.Equal(t, "topk(5, rate(http_requests_total[5m]))", query)
+	require.Equal(t, m.LabelKeys, legend)
+}
+
+func TestCounterConverter_CardinalityLimit(t *testing.T) {
+	m := Metric{
+		LabelKeys:   []string{"handler", "pod"},
+		LabelValues: map[string]int{"handler": 5, "pod": 200},
+		Name:        "http_requests_total",
+		Series:      1000,
+		Type:        textparse.MetricTypeCounter,
+	}
+
+	panels := (&CounterConverter{}).Do(m, Options{CardinalityLimit: 50, CounterChangeFunc: "rate", TimeRange: "5m"})
+	g := panels[0].(Graph)
+	require.Equal(t, "sum by (handler) (rate(http_requests_total[5m]))", g.Queries[0].Query)
+	require.Equal(t, "{{handler}}", g.Legend)
+}
+
+func TestMultiSource_Metrics_Cardinality(t *testing.T) {
+	a := staticSource{{LabelKeys: []string{"pod"}, LabelValues: map[string]int{"pod": 3}, Name: "up", Series: 3}}
+	b := staticSource{{LabelKeys: []string{"pod"}, LabelValues: map[string]int{"pod": 5}, Name: "up", Series: 5}}
+
+	metrics, err := (&MultiSource{Sources: []MetricSource{a, b}}).Metrics()
+	require.NoError(t, err)
+	require.Equal(t, 8, metrics[0].Series)
+	require.Equal(t, map[string]int{"pod": 5}, metrics[0].LabelValues)
+}
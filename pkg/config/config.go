@@ -1,8 +1,10 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/hoisie/mustache"
 	log "github.com/sirupsen/logrus"
@@ -10,21 +12,57 @@ import (
 )
 
 type Config struct {
+	ConverterRules               []ConverterRule
 	Datasource                   string
+	DatasourceLoki               string
+	DrilldownHTTP                HTTPClientConfig
+	DrilldownScrapeTimeout       time.Duration
+	FormatRules                  []FormatRule
 	GrafanaAddress               string
 	GrafanaFolder                string
+	GrafanaPanelsGridColumns     int
 	GrafanaPanelsHeight          int
 	GrafanaPanelsGraphWidth      int
+	GrafanaPanelsLayout          string
 	GrafanaPanelsSinglestatWidth int
+	GrafanaPanelsStyle           string
 	GrafanaPassword              string
 	GrafanaUsername              string
 	LogLevel                     log.Level
+	PrometheusAddress            string
+	PrometheusAPI                string
+	PrometheusHTTP               HTTPClientConfig
+	PrometheusTenant             string
+	TemplateBarGauge             *mustache.Template
 	TemplateDashboard            *mustache.Template
+	TemplateGauge                *mustache.Template
 	TemplateGraph                *mustache.Template
+	TemplateHeatmap              *mustache.Template
 	TemplateRow                  *mustache.Template
 	TemplateSinglestat           *mustache.Template
+	TemplateStat                 *mustache.Template
+	TemplateTable                *mustache.Template
+	TemplateTimeseries           *mustache.Template
 }
 
+const (
+	// PanelStyleLegacy renders the graph and singlestat panels of Grafana.
+	PanelStyleLegacy = "legacy"
+	// PanelStyleModern renders the timeseries and stat panels that replace graph and singlestat in Grafana 7 and later.
+	PanelStyleModern = "modern"
+)
+
+const (
+	// LayoutCompact moves singlestat, stat and gauge panels to a strip at the top of each row and fills all lines.
+	LayoutCompact = "compact"
+	// LayoutFill stretches the panels on a line to fill the whole width of the dashboard.
+	LayoutFill = "fill"
+	// LayoutFlow places panels with their configured width and starts a new line if a panel does not fit.
+	LayoutFlow = "flow"
+	// LayoutGrid places panels in a fixed number of columns of equal width.
+	LayoutGrid = "grid"
+)
+
 func Parse(path string) (cfg Config, _ error) {
 	viper.SetEnvPrefix("ab")
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
@@ -44,48 +82,301 @@ func Parse(path string) (cfg Config, _ error) {
 		return cfg, fmt.Errorf("parse log level: %w", err)
 	}
 
-	dashboardTpl, err := readTemplate("templates.dashboard", dashboardTplDefault)
+	panelsStyle := viper.GetString("grafana.panels.style")
+	switch panelsStyle {
+	case "":
+		panelsStyle = PanelStyleLegacy
+	case PanelStyleLegacy, PanelStyleModern:
+	default:
+		return cfg, fmt.Errorf("unknown panel style %s", panelsStyle)
+	}
+
+	panelsLayout := viper.GetString("grafana.panels.layout")
+	switch panelsLayout {
+	case "":
+		panelsLayout = LayoutFlow
+	case LayoutCompact, LayoutFill, LayoutFlow, LayoutGrid:
+	default:
+		return cfg, fmt.Errorf("unknown panel layout %s", panelsLayout)
+	}
+
+	gridColumns := viper.GetInt("grafana.panels.grid.columns")
+	if panelsLayout == LayoutGrid && (gridColumns < 1 || gridColumns > 24) {
+		return cfg, fmt.Errorf("number of columns of grid layout must be between 1 and 24, got %d", gridColumns)
+	}
+
+	barGaugeTpl, err := readTemplate("templates.bargauge")
+	if err != nil {
+		return cfg, fmt.Errorf("read bargauge template: %w", err)
+	}
+
+	dashboardTpl, err := readTemplate("templates.dashboard")
 	if err != nil {
 		return cfg, fmt.Errorf("read dashboard template: %w", err)
 	}
 
-	graphTpl, err := readTemplate("templates.graph", graphTplDefault)
+	gaugeTpl, err := readTemplate("templates.gauge")
+	if err != nil {
+		return cfg, fmt.Errorf("read gauge template: %w", err)
+	}
+
+	graphTpl, err := readTemplate("templates.graph")
 	if err != nil {
 		return cfg, fmt.Errorf("read graph template: %w", err)
 	}
 
-	rowTpl, err := readTemplate("templates.row", rowTplDefault)
+	heatmapTpl, err := readTemplate("templates.heatmap")
+	if err != nil {
+		return cfg, fmt.Errorf("read heatmap template: %w", err)
+	}
+
+	rowTpl, err := readTemplate("templates.row")
 	if err != nil {
 		return cfg, fmt.Errorf("read row template: %w", err)
 	}
 
-	singlestatTpl, err := readTemplate("templates.singlestat", singlestatTplDefault)
+	singlestatTpl, err := readTemplate("templates.singlestat")
 	if err != nil {
 		return cfg, fmt.Errorf("read singlestat template: %w", err)
 	}
 
+	statTpl, err := readTemplate("templates.stat")
+	if err != nil {
+		return cfg, fmt.Errorf("read stat template: %w", err)
+	}
+
+	tableTpl, err := readTemplate("templates.table")
+	if err != nil {
+		return cfg, fmt.Errorf("read table template: %w", err)
+	}
+
+	timeseriesTpl, err := readTemplate("templates.timeseries")
+	if err != nil {
+		return cfg, fmt.Errorf("read timeseries template: %w", err)
+	}
+
+	var converterRules []ConverterRule
+	err = viper.UnmarshalKey("converters", &converterRules)
+	if err != nil {
+		return cfg, fmt.Errorf("read converter rules: %w", err)
+	}
+
+	for i, r := range converterRules {
+		err := r.Validate()
+		if err != nil {
+			return cfg, fmt.Errorf("converter rule %d: %w", i+1, err)
+		}
+	}
+
+	var formatRules []FormatRule
+	err = viper.UnmarshalKey("formats", &formatRules)
+	if err != nil {
+		return cfg, fmt.Errorf("read format rules: %w", err)
+	}
+
+	for i, r := range formatRules {
+		err := r.Validate()
+		if err != nil {
+			return cfg, fmt.Errorf("format rule %d: %w", i+1, err)
+		}
+	}
+
 	return Config{
+		ConverterRules:               converterRules,
 		Datasource:                   viper.GetString("grafana.datasource"),
+		DatasourceLoki:               viper.GetString("grafana.loki.datasource"),
+		DrilldownHTTP:                readHTTPClientConfig("drilldown"),
+		DrilldownScrapeTimeout:       viper.GetDuration("drilldown.scrape_timeout"),
+		FormatRules:                  formatRules,
 		GrafanaAddress:               viper.GetString("grafana.address"),
 		GrafanaFolder:                viper.GetString("grafana.folder"),
+		GrafanaPanelsGridColumns:     gridColumns,
 		GrafanaPanelsHeight:          viper.GetInt("grafana.panels.height"),
 		GrafanaPanelsGraphWidth:      viper.GetInt("grafana.panels.graph.width"),
+		GrafanaPanelsLayout:          panelsLayout,
 		GrafanaPanelsSinglestatWidth: viper.GetInt("grafana.panels.singlestat.width"),
+		GrafanaPanelsStyle:           panelsStyle,
 		GrafanaPassword:              viper.GetString("grafana.password"),
 		GrafanaUsername:              viper.GetString("grafana.username"),
 		LogLevel:                     logLvl,
+		PrometheusAddress:            viper.GetString("prometheus.address"),
+		PrometheusAPI:                viper.GetString("prometheus.api"),
+		PrometheusHTTP:               readHTTPClientConfig("prometheus"),
+		PrometheusTenant:             viper.GetString("prometheus.tenant"),
+		TemplateBarGauge:             barGaugeTpl,
 		TemplateDashboard:            dashboardTpl,
+		TemplateGauge:                gaugeTpl,
 		TemplateGraph:                graphTpl,
+		TemplateHeatmap:              heatmapTpl,
 		TemplateRow:                  rowTpl,
 		TemplateSinglestat:           singlestatTpl,
+		TemplateStat:                 statTpl,
+		TemplateTable:                tableTpl,
+		TemplateTimeseries:           timeseriesTpl,
 	}, nil
 }
 
-func readTemplate(cfgKey, def string) (*mustache.Template, error) {
-	dashboardTplPath := viper.GetString(cfgKey)
-	if dashboardTplPath == "" {
-		return mustache.ParseString(def)
+// ConverterRule describes the panels to create for metrics.
+// Name is a regular expression. A metric matches if it also has the Type and exposes all Labels.
+type ConverterRule struct {
+	Labels []string         `mapstructure:"labels"`
+	Name   string           `mapstructure:"name"`
+	Panels []ConverterPanel `mapstructure:"panels"`
+	Type   string           `mapstructure:"type"`
+}
+
+// Validate checks that Type is a known type of a metric and that all Panels are valid.
+func (r ConverterRule) Validate() error {
+	err := validateMetricType(r.Type)
+	if err != nil {
+		return err
+	}
+
+	for i, p := range r.Panels {
+		err := p.Validate()
+		if err != nil {
+			return fmt.Errorf("panel %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// ConverterPanel describes one panel created by a ConverterRule.
+// Query and Title are templates of the Go package text/template.
+// Mappings, Max, Min and Thresholds only apply to the panel types timeseries, stat, gauge, bargauge and table.
+type ConverterPanel struct {
+	Format string `mapstructure:"format"`
+	Legend string `mapstructure:"legend"`
+	// Mappings map a value to a text to display instead, e.g. "1" to "up".
+	Mappings   map[string]string    `mapstructure:"mappings"`
+	Max        string               `mapstructure:"max"`
+	Min        string               `mapstructure:"min"`
+	Query      string               `mapstructure:"query"`
+	Thresholds []ConverterThreshold `mapstructure:"thresholds"`
+	Title      string               `mapstructure:"title"`
+	Type       string               `mapstructure:"type"`
+}
+
+// Validate checks that Max, Min and the values of Thresholds are numbers that can be written to JSON
+// and that every threshold has a color.
+func (p ConverterPanel) Validate() error {
+	for _, v := range []string{p.Max, p.Min} {
+		if v != "" && !isJSONNumber(v) {
+			return fmt.Errorf("min or max %s is not a number", v)
+		}
+	}
+
+	for i, t := range p.Thresholds {
+		if t.Color == "" {
+			return fmt.Errorf("threshold %d: color is empty", i+1)
+		}
+
+		// The value of the first step is ignored by Grafana.
+		if i > 0 && !isJSONNumber(t.Value) {
+			return fmt.Errorf("threshold %d: value %s is not a number", i+1, t.Value)
+		}
+	}
+
+	return nil
+}
+
+// isJSONNumber reports whether s is a number in JSON, e.g. "1.5" or "-2e3". "Inf", "NaN" or "0x10" are not.
+func isJSONNumber(s string) bool {
+	if s == "" || strings.TrimSpace(s) != s || !json.Valid([]byte(s)) {
+		return false
+	}
+
+	var n interface{}
+	err := json.Unmarshal([]byte(s), &n)
+	if err != nil {
+		return false
+	}
+
+	_, ok := n.(float64)
+	return ok
+}
+
+// ConverterThreshold colors values of a panel that are equal to or greater than Value.
+type ConverterThreshold struct {
+	Color string `mapstructure:"color"`
+	Value string `mapstructure:"value"`
+}
+
+// FormatRule maps metrics to a format of Grafana.
+// Name and Function are regular expressions. All settings except Format are optional.
+type FormatRule struct {
+	Format   string `mapstructure:"format"`
+	Function string `mapstructure:"function"`
+	Name     string `mapstructure:"name"`
+	Type     string `mapstructure:"type"`
+	Unit     string `mapstructure:"unit"`
+}
+
+// Validate checks that Type is a known type of a metric.
+func (r FormatRule) Validate() error {
+	return validateMetricType(r.Type)
+}
+
+// MetricTypes are the types of metrics in the Prometheus text format and OpenMetrics.
+var MetricTypes = []string{"counter", "gauge", "gaugehistogram", "histogram", "info", "stateset", "summary", "unknown"}
+
+// validateMetricType returns an error if t is neither empty nor one of MetricTypes.
+func validateMetricType(t string) error {
+	if t == "" {
+		return nil
+	}
+
+	for _, mt := range MetricTypes {
+		if t == mt {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown type %s, must be one of %s", t, strings.Join(MetricTypes, ", "))
+}
+
+// HTTPClientConfig configures how autoboard connects to a server.
+// The keys of the settings mirror the HTTP settings of a scrape_config in Prometheus.
+type HTTPClientConfig struct {
+	BasicAuthPassword     string
+	BasicAuthPasswordFile string
+	BasicAuthUsername     string
+	BearerToken           string
+	BearerTokenFile       string
+	Headers               map[string]string
+	ProxyURL              string
+	TLSCAFile             string
+	TLSCertFile           string
+	TLSInsecureSkipVerify bool
+	TLSKeyFile            string
+	TLSServerName         string
+}
+
+func readHTTPClientConfig(prefix string) HTTPClientConfig {
+	return HTTPClientConfig{
+		BasicAuthPassword:     viper.GetString(prefix + ".basic_auth.password"),
+		BasicAuthPasswordFile: viper.GetString(prefix + ".basic_auth.password_file"),
+		BasicAuthUsername:     viper.GetString(prefix + ".basic_auth.username"),
+		BearerToken:           viper.GetString(prefix + ".bearer_token"),
+		BearerTokenFile:       viper.GetString(prefix + ".bearer_token_file"),
+		Headers:               viper.GetStringMapString(prefix + ".headers"),
+		ProxyURL:              viper.GetString(prefix + ".proxy_url"),
+		TLSCAFile:             viper.GetString(prefix + ".tls_config.ca_file"),
+		TLSCertFile:           viper.GetString(prefix + ".tls_config.cert_file"),
+		TLSInsecureSkipVerify: viper.GetBool(prefix + ".tls_config.insecure_skip_verify"),
+		TLSKeyFile:            viper.GetString(prefix + ".tls_config.key_file"),
+		TLSServerName:         viper.GetString(prefix + ".tls_config.server_name"),
+	}
+}
+
+// readTemplate parses the template at the path set in cfgKey.
+// It returns nil if no path is set. The JSON is then created from the model of the dashboard or panel.
+func readTemplate(cfgKey string) (*mustache.Template, error) {
+	tplPath := viper.GetString(cfgKey)
+	if tplPath == "" {
+		return nil, nil
 	}
 
-	return mustache.ParseFile(dashboardTplPath)
+	return mustache.ParseFile(tplPath)
 }
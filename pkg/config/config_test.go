@@ -0,0 +1,70 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_ConverterNumbers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "autoboard")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yml")
+	err = ioutil.WriteFile(path, []byte(`
+log.level: info
+converters:
+  - name: ^up$
+    panels:
+      - type: gauge
+        query: avg(up)
+        max: Inf
+`), 0644)
+	require.NoError(t, err)
+
+	_, err = Parse(path)
+	require.EqualError(t, err, "converter rule 1: panel 1: min or max Inf is not a number")
+}
+
+func TestParse_UnknownTypes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "autoboard")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yml")
+	err = ioutil.WriteFile(path, []byte(`
+log.level: info
+formats:
+  - type: counters
+    format: short
+`), 0644)
+	require.NoError(t, err)
+
+	_, err = Parse(path)
+	require.EqualError(t, err, "format rule 1: unknown type counters, must be one of counter, gauge, gaugehistogram, histogram, info, stateset, summary, unknown")
+
+	err = ioutil.WriteFile(path, []byte(`
+log.level: info
+converters:
+  - name: ^up$
+    type: gauges
+    panels:
+      - query: up
+`), 0644)
+	require.NoError(t, err)
+
+	_, err = Parse(path)
+	require.EqualError(t, err, "converter rule 1: unknown type gauges, must be one of counter, gauge, gaugehistogram, histogram, info, stateset, summary, unknown")
+}
+
+func TestIsJSONNumber(t *testing.T) {
+	for _, v := range []string{"0", "1.5", "-2e3", "100"} {
+		require.True(t, isJSONNumber(v), v)
+	}
+
+	for _, v := range []string{"", "Inf", "+Inf", "NaN", "0x1p3", "1_0", " 1", "01", `"1"`, "true"} {
+		require.False(t, isJSONNumber(v), v)
+	}
+}
@@ -2,6 +2,8 @@ package v1
 
 import (
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
 
 	"github.com/prometheus/prometheus/pkg/textparse"
@@ -17,9 +19,23 @@ type MetricConverter interface {
 
 // Options are passed to the Do() method of each MetricConverter.
 type Options struct {
+	// CardinalityLimit is the number of series of a Metric above which queries aggregate the series.
+	CardinalityLimit  int
 	CounterChangeFunc string
-	Labels            []string
-	TimeRange         string
+	// CombineQuantiles displays all quantiles of a histogram in one panel.
+	CombineQuantiles bool
+	// FormatMapper finds the format of a panel. DefaultFormatMapper is used if it is nil.
+	FormatMapper *FormatMapper
+	// Heatmap controls whether a heatmap of the buckets of a histogram is created. One of the HeatmapMode* constants.
+	Heatmap string
+	// HistogramBy are the labels by which the buckets of a histogram are aggregated. All labels are used if it is empty.
+	HistogramBy []string
+	Labels      []string
+	// Quantiles are the quantiles to display for a histogram. DefaultQuantiles are used if it is empty.
+	Quantiles []float64
+	TimeRange string
+	// TopK is the number of series to select if aggregating by labels does not stay within CardinalityLimit.
+	TopK int
 }
 
 // CounterConverter handles metrics of type Counter.
@@ -33,8 +49,10 @@ func (cc *CounterConverter) Can(m Metric) bool {
 
 // Do implements MetricConverter.
 func (cc *CounterConverter) Do(m Metric, o Options) []Panel {
+	query := fmt.Sprintf("%s(%s%s[%s])", o.CounterChangeFunc, m.Name, labelSelectors(o.Labels), o.TimeRange)
+	query, legendKeys := limitCardinality(m, o, query, m.LabelKeys)
 	legend := []string{}
-	for _, l := range m.LabelKeys {
+	for _, l := range legendKeys {
 		legend = append(legend, fmt.Sprintf("{{%s}}", l))
 	}
 
@@ -47,12 +65,12 @@ func (cc *CounterConverter) Do(m Metric, o Options) []Panel {
 
 	g := Graph{}
 	g.Description = string(m.Help)
-	g.Format = FindRangeFormat(m.Name)
+	g.Format = o.findFunctionFormat(m, o.CounterChangeFunc)
 	g.HasLegend = hasLegend
 	g.Legend = strings.Join(legend, " ")
 	g.Title = fmt.Sprintf("%s %s over %s", string(m.Name), o.CounterChangeFunc, o.TimeRange)
 	g.Queries = []GraphQuery{
-		{Query: fmt.Sprintf("%s(%s%s[%s])", o.CounterChangeFunc, m.Name, labelSelectors(o.Labels), o.TimeRange)},
+		{Query: query},
 	}
 	return []Panel{g}
 }
@@ -70,7 +88,7 @@ func (gc *GaugeConverter) Can(m Metric) bool {
 func (gc *GaugeConverter) Do(m Metric, o Options) []Panel {
 	s := Singlestat{}
 	s.Description = string(m.Help)
-	s.Format = FindFormat(m.Name)
+	s.Format = o.findFormat(m)
 	s.Query = m.Name + labelSelectors(o.Labels)
 	s.Title = m.Name
 	s.ValueName = "current"
@@ -102,7 +120,7 @@ func (gd *GaugeDerivConverter) Do(m Metric, o Options) []Panel {
 
 	g := Graph{}
 	g.Description = string(m.Help)
-	g.Format = FindRangeFormat(m.Name)
+	g.Format = o.findFunctionFormat(m, "deriv")
 	g.HasLegend = hasLegend
 	g.Legend = strings.Join(legend, " ")
 	g.Title = fmt.Sprintf("%s %s over %s", string(m.Name), "deriv", o.TimeRange)
@@ -132,7 +150,7 @@ func (gt *GaugeTimestampConverter) Do(m Metric, o Options) []Panel {
 
 	s := Singlestat{}
 	s.Description = string(m.Help)
-	s.Format = FindFormat(m.Name)
+	s.Format = o.findFormat(m)
 	s.Query = query
 	s.Title = m.Name
 	s.ValueName = "current"
@@ -141,12 +159,13 @@ func (gt *GaugeTimestampConverter) Do(m Metric, o Options) []Panel {
 
 // GaugeInfoConverter handles Metrics whose suffix suggests that information is stored in labels not in the value.
 // Examples of such metrics are "go_info" or "prometheus_build_info".
+// It also handles Metrics of the OpenMetrics type Info.
 // It returns one Singlestat Panel per label key.
 type GaugeInfoConverter struct{}
 
 // Can implements MetricConverter.
 func (gi *GaugeInfoConverter) Can(m Metric) bool {
-	return m.Type == textparse.MetricTypeGauge && strings.HasSuffix(m.Name, "_info")
+	return (m.Type == textparse.MetricTypeGauge && strings.HasSuffix(m.Name, "_info")) || m.Type == textparse.MetricTypeInfo
 }
 
 // Do implements MetricConverter.
@@ -166,8 +185,24 @@ func (gi *GaugeInfoConverter) Do(m Metric, o Options) []Panel {
 	return panels
 }
 
+// Values of Options.Heatmap.
+const (
+	// HeatmapModeNone does not create a heatmap. An empty value is treated the same.
+	HeatmapModeNone = "none"
+	// HeatmapModeAdd creates a heatmap in addition to the graphs of the quantiles.
+	HeatmapModeAdd = "add"
+	// HeatmapModeReplace creates a heatmap instead of the graphs of the quantiles.
+	HeatmapModeReplace = "replace"
+)
+
+// DefaultQuantiles are the quantiles displayed for a histogram if Options.Quantiles is empty.
+var DefaultQuantiles = []float64{0.5, 0.9, 0.99}
+
 // HistogramConverter handles metrics of type Histogram.
-// It returns four Panels of type Graph, one for avg, p50, p95 and p99.
+// It returns one Panel of type Graph for the average and one for each quantile in Options.Quantiles, e.g. p50, p90
+// and p99. If Options.CombineQuantiles is set, all quantiles are displayed in one Panel.
+// The buckets are aggregated by "le" and the labels in Options.HistogramBy or, if it is empty, all labels of the Metric.
+// Depending on Options.Heatmap, it adds a Panel of type Heatmap or replaces the quantiles with it.
 type HistogramConverter struct{}
 
 // Can implements MetricConverter.
@@ -177,12 +212,9 @@ func (h *HistogramConverter) Can(m Metric) bool {
 
 // Do implements MetricConverter.
 func (h *HistogramConverter) Do(m Metric, o Options) []Panel {
+	by := histogramBy(m, o.HistogramBy)
 	legend := []string{}
-	for _, lk := range m.LabelKeys {
-		if lk == "le" {
-			continue
-		}
-
+	for _, lk := range by {
 		legend = append(legend, fmt.Sprintf("{{%s}}", lk))
 	}
 
@@ -193,50 +225,166 @@ func (h *HistogramConverter) Do(m Metric, o Options) []Panel {
 	}
 
 	legendFormatted := strings.Join(legend, " ")
-
 	selectors := labelSelectors(o.Labels)
+	byClause := ""
+	if len(by) > 0 {
+		byClause = fmt.Sprintf(" by (%s)", strings.Join(by, ", "))
+	}
 
 	avg := Graph{}
 	avg.Description = string(m.Help)
-	avg.Format = FindFormat(m.Name)
+	avg.Format = o.findFormat(m)
 	avg.HasLegend = hasLegend
 	avg.Legend = legendFormatted
 	avg.Title = fmt.Sprintf("%s avg", string(m.Name))
 	avg.Queries = []GraphQuery{
-		{Query: fmt.Sprintf("%s_sum%s / %s_count%s", m.Name, selectors, m.Name, selectors)},
+		{Query: fmt.Sprintf("sum%s (rate(%s_sum%s[%s])) / sum%s (rate(%s_count%s[%s]))", byClause, m.Name, selectors, o.TimeRange, byClause, m.Name, selectors, o.TimeRange)},
 	}
 
-	p50 := Graph{}
-	p50.Description = string(m.Help)
-	p50.Format = FindFormat(m.Name)
-	p50.HasLegend = hasLegend
-	p50.Legend = legendFormatted
-	p50.Title = fmt.Sprintf("%s p50", string(m.Name))
-	p50.Queries = []GraphQuery{
-		{Query: fmt.Sprintf("histogram_quantile(0.5, rate(%s_bucket%s[%s]))", m.Name, selectors, o.TimeRange)},
+	heatmap := Heatmap{
+		Description: string(m.Help),
+		Format:      o.findFormat(m),
+		Legend:      "{{le}}",
+		Query:       fmt.Sprintf("sum by (le) (rate(%s_bucket%s[%s]))", m.Name, selectors, o.TimeRange),
+		Title:       fmt.Sprintf("%s heatmap", string(m.Name)),
 	}
 
-	p90 := Graph{}
-	p90.Description = string(m.Help)
-	p90.Format = FindFormat(m.Name)
-	p90.HasLegend = hasLegend
-	p90.Legend = legendFormatted
-	p90.Title = fmt.Sprintf("%s p90", string(m.Name))
-	p90.Queries = []GraphQuery{
-		{Query: fmt.Sprintf("histogram_quantile(0.9, rate(%s_bucket%s[%s]))", m.Name, selectors, o.TimeRange)},
+	if o.Heatmap == HeatmapModeReplace {
+		return []Panel{avg, heatmap}
 	}
 
-	p99 := Graph{}
-	p99.Description = string(m.Help)
-	p99.Format = FindFormat(m.Name)
-	p99.HasLegend = hasLegend
-	p99.Legend = legendFormatted
-	p99.Title = fmt.Sprintf("%s p99", string(m.Name))
-	p99.Queries = []GraphQuery{
-		{Query: fmt.Sprintf("histogram_quantile(0.99, rate(%s_bucket%s[%s]))", m.Name, selectors, o.TimeRange)},
+	quantiles := o.Quantiles
+	if len(quantiles) == 0 {
+		quantiles = DefaultQuantiles
+	}
+
+	bucketBy := strings.Join(append([]string{"le"}, by...), ", ")
+	panels := []Panel{avg}
+	if o.CombineQuantiles {
+		combined := Graph{}
+		combined.Description = string(m.Help)
+		combined.Format = o.findFormat(m)
+		combined.HasLegend = true
+		combined.Title = fmt.Sprintf("%s quantiles", string(m.Name))
+		for _, q := range quantiles {
+			combined.Queries = append(combined.Queries, GraphQuery{
+				Legend: strings.Join(append([]string{quantileName(q)}, legend[:len(by)]...), " "),
+				Query:  fmt.Sprintf("histogram_quantile(%s, sum by (%s) (rate(%s_bucket%s[%s])))", formatQuantile(q), bucketBy, m.Name, selectors, o.TimeRange),
+			})
+		}
+
+		panels = append(panels, combined)
+	} else {
+		for _, q := range quantiles {
+			g := Graph{}
+			g.Description = string(m.Help)
+			g.Format = o.findFormat(m)
+			g.HasLegend = hasLegend
+			g.Legend = legendFormatted
+			g.Title = fmt.Sprintf("%s %s", string(m.Name), quantileName(q))
+			g.Queries = []GraphQuery{
+				{Query: fmt.Sprintf("histogram_quantile(%s, sum by (%s) (rate(%s_bucket%s[%s])))", formatQuantile(q), bucketBy, m.Name, selectors, o.TimeRange)},
+			}
+			panels = append(panels, g)
+		}
+	}
+
+	if o.Heatmap == HeatmapModeAdd {
+		panels = append(panels, heatmap)
+	}
+
+	return panels
+}
+
+// histogramBy returns the labels by which the buckets of a histogram are aggregated.
+// Labels that the Metric does not expose are ignored.
+func histogramBy(m Metric, by []string) []string {
+	result := []string{}
+	for _, lk := range m.LabelKeys {
+		if lk == "le" {
+			continue
+		}
+
+		if len(by) > 0 && !containsString(by, lk) {
+			continue
+		}
+
+		result = append(result, lk)
 	}
 
-	return []Panel{avg, p50, p90, p99}
+	return result
+}
+
+// formatQuantile formats a quantile for use in a PromQL query, e.g. "0.99".
+func formatQuantile(q float64) string {
+	return strconv.FormatFloat(q, 'f', -1, 64)
+}
+
+// quantileName returns the percentile of a quantile, e.g. "p99" for 0.99 or "p99.9" for 0.999.
+func quantileName(q float64) string {
+	return "p" + strconv.FormatFloat(math.Round(q*100000)/1000, 'f', -1, 64)
+}
+
+// SummaryConverter handles metrics of type Summary.
+// It returns three Panels of type Graph: one that displays the quantiles exposed by the summary, one for the average
+// calculated from "_sum" and "_count" and one for the rate of "_count".
+type SummaryConverter struct{}
+
+// Can implements MetricConverter.
+func (sc *SummaryConverter) Can(m Metric) bool {
+	return m.Type == textparse.MetricTypeSummary
+}
+
+// Do implements MetricConverter.
+func (sc *SummaryConverter) Do(m Metric, o Options) []Panel {
+	legend := []string{}
+	for _, lk := range m.LabelKeys {
+		if lk == "quantile" {
+			continue
+		}
+
+		legend = append(legend, fmt.Sprintf("{{%s}}", lk))
+	}
+
+	hasLegend := true
+	if len(legend) == 0 {
+		legend = append(legend, "{{instance}}")
+		hasLegend = false
+	}
+
+	selectors := labelSelectors(o.Labels)
+
+	quantiles := Graph{}
+	quantiles.Description = string(m.Help)
+	quantiles.Format = o.findFormat(m)
+	quantiles.HasLegend = true
+	quantiles.Legend = strings.Join(append([]string{"{{quantile}}"}, legend...), " ")
+	quantiles.Title = fmt.Sprintf("%s quantiles", string(m.Name))
+	quantiles.Queries = []GraphQuery{
+		{Query: m.Name + selectors},
+	}
+
+	avg := Graph{}
+	avg.Description = string(m.Help)
+	avg.Format = o.findFormat(m)
+	avg.HasLegend = hasLegend
+	avg.Legend = strings.Join(legend, " ")
+	avg.Title = fmt.Sprintf("%s avg", string(m.Name))
+	avg.Queries = []GraphQuery{
+		{Query: fmt.Sprintf("rate(%s_sum%s[%s]) / rate(%s_count%s[%s])", m.Name, selectors, o.TimeRange, m.Name, selectors, o.TimeRange)},
+	}
+
+	count := Graph{}
+	count.Description = string(m.Help)
+	count.Format = o.findFunctionFormat(Metric{Name: m.Name + "_count", Type: textparse.MetricTypeCounter}, o.CounterChangeFunc)
+	count.HasLegend = hasLegend
+	count.Legend = strings.Join(legend, " ")
+	count.Title = fmt.Sprintf("%s_count %s over %s", string(m.Name), o.CounterChangeFunc, o.TimeRange)
+	count.Queries = []GraphQuery{
+		{Query: fmt.Sprintf("%s(%s_count%s[%s])", o.CounterChangeFunc, m.Name, selectors, o.TimeRange)},
+	}
+
+	return []Panel{quantiles, avg, count}
 }
 
 // GaugeWithLabelsConverter handles metrics of type Gauge that define labels.
@@ -250,19 +398,19 @@ func (gl *GaugeWithLabelsConverter) Can(m Metric) bool {
 
 // Do implements MetricConverter.
 func (gl *GaugeWithLabelsConverter) Do(m Metric, o Options) []Panel {
+	query, legendKeys := limitCardinality(m, o, m.Name+labelSelectors(o.Labels), m.LabelKeys)
 	legend := []string{}
-	for _, lk := range m.LabelKeys {
+	for _, lk := range legendKeys {
 		legend = append(legend, fmt.Sprintf("{{%s}}", lk))
 	}
 
-	query := m.Name + labelSelectors(o.Labels)
 	if strings.HasSuffix(m.Name, "_timestamp_seconds") {
 		query = query + " * 1000"
 	}
 
 	g := Graph{}
 	g.Description = string(m.Help)
-	g.Format = FindFormat(m.Name)
+	g.Format = o.findFormat(m)
 	g.HasLegend = true
 	g.Legend = strings.Join(legend, " ")
 	g.Title = m.Name
@@ -272,15 +420,172 @@ func (gl *GaugeWithLabelsConverter) Do(m Metric, o Options) []Panel {
 	return []Panel{g}
 }
 
-func labelSelectors(labels []string) string {
-	if len(labels) == 0 {
-		return ""
+// StatesetConverter handles metrics of the OpenMetrics type StateSet.
+// A StateSet exposes one series per state. The label that holds the state has the same name as the metric.
+// It returns one Graph Panel that displays which states are active over time. If the Metric does not define any other
+// labels, it also returns a Singlestat Panel that displays the name of the active state.
+type StatesetConverter struct{}
+
+// Can implements MetricConverter.
+func (sc *StatesetConverter) Can(m Metric) bool {
+	return m.Type == textparse.MetricTypeStateset
+}
+
+// Do implements MetricConverter.
+func (sc *StatesetConverter) Do(m Metric, o Options) []Panel {
+	legend := []string{}
+	for _, lk := range m.LabelKeys {
+		legend = append(legend, fmt.Sprintf("{{%s}}", lk))
 	}
 
-	selectors := []string{}
+	query := fmt.Sprintf("%s%s == 1", m.Name, labelSelectors(o.Labels))
+	panels := []Panel{}
+	if len(m.LabelKeys) == 1 && m.LabelKeys[0] == m.Name {
+		s := Singlestat{}
+		s.Description = string(m.Help)
+		s.Format = defaultFormat
+		s.Legend = fmt.Sprintf("{{%s}}", m.Name)
+		s.Query = query
+		s.Title = fmt.Sprintf("%s current state", m.Name)
+		s.ValueName = "name"
+		panels = append(panels, s)
+	}
+
+	g := Graph{}
+	g.Description = string(m.Help)
+	g.Format = "none"
+	g.HasLegend = true
+	g.Legend = strings.Join(legend, " ")
+	g.Title = fmt.Sprintf("%s active states", m.Name)
+	g.Queries = []GraphQuery{
+		{Query: query},
+	}
+	return append(panels, g)
+}
+
+// GaugeHistogramConverter handles metrics of the OpenMetrics type GaugeHistogram.
+// The buckets of a GaugeHistogram are gauges. No rate is applied to them.
+// It returns two Panels of type Graph, one that displays the distribution of the buckets and one for the average.
+type GaugeHistogramConverter struct{}
+
+// Can implements MetricConverter.
+func (gh *GaugeHistogramConverter) Can(m Metric) bool {
+	return m.Type == textparse.MetricTypeGaugeHistogram
+}
+
+// Do implements MetricConverter.
+func (gh *GaugeHistogramConverter) Do(m Metric, o Options) []Panel {
+	legend := []string{}
+	for _, lk := range m.LabelKeys {
+		if lk == "le" {
+			continue
+		}
+
+		legend = append(legend, fmt.Sprintf("{{%s}}", lk))
+	}
+
+	hasLegend := true
+	if len(legend) == 0 {
+		legend = append(legend, "{{instance}}")
+		hasLegend = false
+	}
+
+	selectors := labelSelectors(o.Labels)
+
+	buckets := Graph{}
+	buckets.Description = string(m.Help)
+	buckets.Format = defaultFormat
+	buckets.HasLegend = true
+	buckets.Legend = strings.Join(append([]string{"le={{le}}"}, legend...), " ")
+	buckets.Title = fmt.Sprintf("%s buckets", m.Name)
+	buckets.Queries = []GraphQuery{
+		{Query: fmt.Sprintf("%s_bucket%s", m.Name, selectors)},
+	}
+
+	avg := Graph{}
+	avg.Description = string(m.Help)
+	avg.Format = o.findFormat(m)
+	avg.HasLegend = hasLegend
+	avg.Legend = strings.Join(legend, " ")
+	avg.Title = fmt.Sprintf("%s avg", m.Name)
+	avg.Queries = []GraphQuery{
+		{Query: fmt.Sprintf("%s_gsum%s / %s_gcount%s", m.Name, selectors, m.Name, selectors)},
+	}
+
+	return []Panel{buckets, avg}
+}
+
+// UnknownConverter handles metrics of type Unknown, i.e. metrics that are untyped or do not declare a type.
+// It returns one Panel of type Graph. The function of counters is applied if the name of the Metric ends with "_total",
+// which suggests that it is a counter. The value of the Metric is displayed as is otherwise.
+type UnknownConverter struct{}
+
+// Can implements MetricConverter.
+func (uc *UnknownConverter) Can(m Metric) bool {
+	return m.Type == textparse.MetricTypeUnknown
+}
+
+// Do implements MetricConverter.
+func (uc *UnknownConverter) Do(m Metric, o Options) []Panel {
+	if strings.HasSuffix(m.Name, "_total") {
+		cc := &CounterConverter{}
+		return cc.Do(m, o)
+	}
+
+	legend := []string{}
+	for _, lk := range m.LabelKeys {
+		legend = append(legend, fmt.Sprintf("{{%s}}", lk))
+	}
+
+	hasLegend := true
+	if len(legend) == 0 {
+		legend = append(legend, "{{instance}}")
+		hasLegend = false
+	}
+
+	g := Graph{}
+	g.Description = string(m.Help)
+	g.Format = o.findFormat(m)
+	g.HasLegend = hasLegend
+	g.Legend = strings.Join(legend, " ")
+	g.Title = m.Name
+	g.Queries = []GraphQuery{
+		{Query: m.Name + labelSelectors(o.Labels)},
+	}
+	return []Panel{g}
+}
+
+// labelSelectors returns the label selectors of the variables of the labels, e.g. {instance=~"$instance"}.
+func labelSelectors(labels []string) string {
+	return addLabelMatchers("", labels)
+}
+
+// addLabelMatchers adds the matchers of the variables of the labels to a series selector,
+// e.g. up{job="node"} becomes up{job="node",instance=~"$instance"}.
+func addLabelMatchers(selector string, labels []string, matchers ...string) string {
+	all := []string{}
 	for _, l := range labels {
-		selectors = append(selectors, fmt.Sprintf(`%s=\"$%s\"`, l, l))
+		all = append(all, labelMatcher(l))
 	}
 
-	return "{" + strings.Join(selectors, ",") + "}"
+	all = append(all, matchers...)
+	if len(all) == 0 {
+		return selector
+	}
+
+	joined := strings.Join(all, ",")
+	switch {
+	case strings.HasSuffix(selector, "{}"):
+		return strings.TrimSuffix(selector, "}") + joined + "}"
+	case strings.HasSuffix(selector, "}"):
+		return strings.TrimSuffix(selector, "}") + "," + joined + "}"
+	default:
+		return selector + "{" + joined + "}"
+	}
+}
+
+// labelMatcher returns the matcher of the variable of a label.
+// It matches a regular expression because the variable can have more than one value or "All" selected.
+func labelMatcher(label string) string {
+	return fmt.Sprintf(`%s=~"$%s"`, label, label)
 }
@@ -3,11 +3,8 @@ package v1
 import (
 	"fmt"
 	"io"
-	"io/ioutil"
-	"net/http"
 	"sort"
 	"strings"
-	"time"
 
 	"github.com/prometheus/prometheus/pkg/labels"
 	"github.com/prometheus/prometheus/pkg/textparse"
@@ -21,18 +18,65 @@ const (
 
 // A Metric holds data parsed from the metric endpoint of a service.
 type Metric struct {
+	// Group is the name of the row the Metric is put into.
+	// If it is empty, the row is derived from the name of the Metric.
+	Group     string
 	Help      string
 	LabelKeys []string
-	Name      string
-	Type      textparse.MetricType
+	// LabelValues is the number of distinct values of each label key. It is nil if the MetricSource does not know it.
+	LabelValues map[string]int
+	Name        string
+	// Series is the number of series of the Metric. It is 0 if the MetricSource does not know it.
+	Series int
+	Type   textparse.MetricType
+	// Unit is declared via the UNIT metadata of OpenMetrics, e.g. "seconds" or "bytes".
+	Unit string
 }
 
 // Groups are Metrics grouped by a common criteria.
 type Groups map[string][]Metric
 
+// A MetricSource reads the Metrics for which panels are created.
+type MetricSource interface {
+	Metrics() ([]Metric, error)
+}
+
 // Drilldown main entrypoint for creating a drilldown dashboard.
 type Drilldown struct {
-	Converters []MetricConverter
+	// CardinalityLimit is the number of series of a Metric above which queries aggregate the series.
+	// Queries never aggregate if it is 0.
+	CardinalityLimit int
+	// CollapseRows collapses all rows except the row "General".
+	CollapseRows bool
+	// CollapseRowsAbove collapses rows that contain more than this number of panels. Disabled if it is 0.
+	CollapseRowsAbove int
+	// CombineQuantiles displays all quantiles of a histogram in one panel.
+	CombineQuantiles bool
+	Converters       []MetricConverter
+	// Heatmap controls whether histograms are displayed as a heatmap. One of the HeatmapMode* constants.
+	Heatmap string
+	// HistogramBy are the labels by which the buckets of a histogram are aggregated. All labels are used if it is empty.
+	HistogramBy []string
+	// Presets replace the panels of well-known metrics with hand-tuned panels.
+	Presets []Preset
+	// Quantiles are the quantiles to display for a histogram. DefaultQuantiles are used if it is empty.
+	Quantiles []float64
+	// RED enables the detection of metrics of requests. A RED row replaces their generic panels.
+	RED bool
+	// REDBy is the label by which the panels of a RED row aggregate, e.g. "handler".
+	REDBy string
+	// RepeatBy is a label by which metrics are repeated. Metrics with the label are put into rows that Grafana repeats
+	// for every selected value of the label. Disabled if it is empty.
+	RepeatBy string
+	// RuleConverters are created from the rules in the config file. They are tried before Converters.
+	RuleConverters []MetricConverter
+	// TopK is the number of series to select if aggregating by labels does not reduce the series below CardinalityLimit.
+	TopK int
+	// USE enables the detection of metrics of resources. A USE row replaces their generic panels.
+	USE bool
+	// VariableQuery is the query from which variables read their values, e.g. up{job="node"}.
+	// A query is derived from the metrics of the dashboard if it is empty.
+	VariableQuery string
 }
 
 // NewDrilldown returns an instance of Drilldown and adds all MetricConverters.
@@ -40,51 +84,121 @@ func NewDrilldown() *Drilldown {
 	return &Drilldown{
 		Converters: []MetricConverter{
 			&HistogramConverter{},
+			&SummaryConverter{},
 			&GaugeInfoConverter{},
 			&GaugeDerivConverter{},
 			&GaugeTimestampConverter{},
 			&GaugeWithLabelsConverter{},
 			&GaugeConverter{},
 			&CounterConverter{},
+			&StatesetConverter{},
+			&GaugeHistogramConverter{},
+			&UnknownConverter{},
 		},
+		TopK: 10,
 	}
 }
 
-// Run contains all the steps necessary to turn a Prometheus endpoint into a dashboard.
-func (d *Drilldown) Run(cfg config.Config, counterChangeFunc, endpoint string, groupLevel int, labels []string, title, prefix, timeRange string) error {
+// Run contains all the steps necessary to turn the Metrics read from a MetricSource into a dashboard.
+func (d *Drilldown) Run(cfg config.Config, counterChangeFunc string, source MetricSource, groupLevel int, labels []string, title, prefix, timeRange string) error {
 	log.SetLevel(cfg.LogLevel)
+	switch d.Heatmap {
+	case "", HeatmapModeNone, HeatmapModeAdd, HeatmapModeReplace:
+	default:
+		return fmt.Errorf("unknown heatmap mode %s", d.Heatmap)
+	}
+
+	for _, q := range d.Quantiles {
+		if q <= 0 || q >= 1 {
+			return fmt.Errorf("quantile %v is not between 0 and 1", q)
+		}
+	}
+
+	if d.RepeatBy != "" && containsString(labels, d.RepeatBy) {
+		return fmt.Errorf("repeat-by label %s is also a selector", d.RepeatBy)
+	}
 
-	c := &http.Client{
-		Timeout: 5 * time.Second,
+	formatMapper, err := NewFormatMapper(cfg.FormatRules)
+	if err != nil {
+		return fmt.Errorf("create format rules: %w", err)
 	}
 
-	resp, err := c.Get(endpoint)
+	d.RuleConverters, err = NewRuleConverters(cfg.ConverterRules)
 	if err != nil {
-		return fmt.Errorf("reading Prometheus endpoint %s: %v", endpoint, err)
+		return fmt.Errorf("create converters from rules: %w", err)
+	}
+
+	metrics, err := source.Metrics()
+	if err != nil {
+		return err
+	}
+
+	options := Options{
+		CardinalityLimit:  d.CardinalityLimit,
+		CombineQuantiles:  d.CombineQuantiles,
+		CounterChangeFunc: counterChangeFunc,
+		FormatMapper:      formatMapper,
+		Heatmap:           d.Heatmap,
+		HistogramBy:       d.HistogramBy,
+		Labels:            labels,
+		Quantiles:         d.Quantiles,
+		TimeRange:         timeRange,
+		TopK:              d.TopK,
+	}
+	metrics = filterMetrics(metrics, prefix)
+	variableQuery := d.VariableQuery
+	if variableQuery == "" {
+		variableQuery = queryFromMetrics(metrics)
 	}
 
-	defer resp.Body.Close()
-	b, err := ioutil.ReadAll(resp.Body)
+	panels, metrics, err := applyPresets(d.Presets, metrics, options)
 	if err != nil {
-		return fmt.Errorf("reading body of Prometheus endpoint %s: %v", endpoint, err)
+		return fmt.Errorf("apply presets: %w", err)
+	}
+
+	if d.RED {
+		var services []REDService
+		services, metrics = detectREDServices(metrics, d.REDBy)
+		for _, svc := range services {
+			panels = append(panels, svc.Panels(options)...)
+		}
+	}
+
+	if d.USE {
+		var resources []USEResource
+		resources, metrics = detectUSEResources(metrics)
+		for _, r := range resources {
+			panels = append(panels, r.Panels(options)...)
+		}
+	}
+
+	var repeated []Metric
+	if d.RepeatBy != "" {
+		repeated, metrics = splitMetricsByLabel(metrics, d.RepeatBy)
 	}
 
-	metrics := parseMetrics(b, resp.Header.Get("Content-Type"), prefix)
 	groups := groupMetrics(metrics, groupLevel)
-	panels := d.convertGroupsToPanels(groups, Options{counterChangeFunc, labels, timeRange})
-	r := &Renderer{
-		dashboardTpl:         cfg.TemplateDashboard,
-		datasource:           cfg.Datasource,
-		graphTpl:             cfg.TemplateGraph,
-		panelHeight:          cfg.GrafanaPanelsHeight,
-		panelWidthGraph:      cfg.GrafanaPanelsGraphWidth,
-		panelWidthSinglestat: cfg.GrafanaPanelsSinglestatWidth,
-		rowTpl:               cfg.TemplateRow,
-		singlestatTpl:        cfg.TemplateSinglestat,
+	panels = append(panels, d.convertGroupsToPanels(groups, options)...)
+	if len(repeated) > 0 {
+		repeatOptions := options
+		repeatOptions.Labels = append(append([]string{}, labels...), d.RepeatBy)
+		repeatedPanels := d.convertGroupsToPanels(groupMetrics(repeated, groupLevel), repeatOptions)
+		panels = append(panels, repeatRows(repeatedPanels, d.RepeatBy)...)
 	}
+
+	panels = collapseRows(panels, d.CollapseRows, d.CollapseRowsAbove)
+	r := newRenderer(cfg)
 	db := Dashboard{Title: title}
-	db.Variables = labelsToVariables(cfg.Datasource, labels, queryFromPanels(panels))
-	s := r.Render(db, panels)
+	db.Variables = labelsToVariables(cfg.Datasource, labels, variableQuery)
+	if len(repeated) > 0 {
+		repeatQuery := addLabelMatchers(seriesName(repeated[0]), labels)
+		db.Variables = append(db.Variables, labelsToVariables(cfg.Datasource, []string{d.RepeatBy}, repeatQuery)...)
+	}
+	s, err := r.Render(db, panels)
+	if err != nil {
+		return fmt.Errorf("render drilldown dashboard: %w", err)
+	}
+
 	gf := &Grafana{
 		Address:  cfg.GrafanaAddress,
 		Password: cfg.GrafanaPassword,
@@ -105,6 +219,7 @@ func (d *Drilldown) convertGroupsToPanels(groups Groups, options Options) []Pane
 	}
 
 	sort.Strings(groupKeys)
+	converters := append(append([]MetricConverter{}, d.RuleConverters...), d.Converters...)
 	panels := []Panel{}
 	for _, name := range groupKeys {
 		metrics := groups[name]
@@ -118,7 +233,7 @@ func (d *Drilldown) convertGroupsToPanels(groups Groups, options Options) []Pane
 		}
 
 		for _, m := range metrics {
-			c := findConverter(m, d.Converters)
+			c := findConverter(m, converters)
 			if c == nil {
 				log.Debugf("no converter found for metric %s (%s)", string(m.Name), m.Type)
 				continue
@@ -132,9 +247,83 @@ func (d *Drilldown) convertGroupsToPanels(groups Groups, options Options) []Pane
 	return panels
 }
 
-func parseMetrics(b []byte, contentType string, prefix string) []Metric {
+// splitMetricsByLabel returns the metrics that have the label and the metrics that do not have it.
+func splitMetricsByLabel(metrics []Metric, label string) (with []Metric, without []Metric) {
+	for _, m := range metrics {
+		if containsString(m.LabelKeys, label) {
+			with = append(with, m)
+		} else {
+			without = append(without, m)
+		}
+	}
+
+	return with, without
+}
+
+// repeatRows makes Grafana repeat every row for each selected value of the variable of the label.
+// The value of the label is appended to the title of a row.
+func repeatRows(panels []Panel, label string) []Panel {
+	result := make([]Panel, len(panels))
+	for i, p := range panels {
+		if row, ok := p.(Row); ok {
+			row.Repeat = label
+			row.Title = fmt.Sprintf("%s $%s", row.Title, label)
+			p = row
+		}
+
+		result[i] = p
+	}
+
+	return result
+}
+
+// seriesName returns the name of a series of the Metric.
+// Histograms and summaries do not expose a series with the name of the metric.
+func seriesName(m Metric) string {
+	switch m.Type {
+	case textparse.MetricTypeGaugeHistogram, textparse.MetricTypeHistogram:
+		return m.Name + "_bucket"
+	case textparse.MetricTypeSummary:
+		return m.Name + "_count"
+	default:
+		return m.Name
+	}
+}
+
+// collapseRows collapses all rows except the row "General" if all is true.
+// It collapses rows that contain more than above panels if above is greater than 0.
+func collapseRows(panels []Panel, all bool, above int) []Panel {
+	result := make([]Panel, len(panels))
+	copy(result, panels)
+	for i, p := range result {
+		row, ok := p.(Row)
+		if !ok {
+			continue
+		}
+
+		count := 0
+		for _, next := range result[i+1:] {
+			if next.Type() == PanelTypeRow {
+				break
+			}
+
+			count++
+		}
+
+		if (all && row.Title != "General") || (above > 0 && count > above) {
+			row.Collapsed = true
+			result[i] = row
+		}
+	}
+
+	return result
+}
+
+func parseMetrics(b []byte, contentType string) []Metric {
 	metrics := []Metric{}
+	counters := []*cardinalityCounter{}
 	cm := Metric{}
+	family := ""
 	p := textparse.New(b, contentType)
 	for {
 		et, err := p.Next()
@@ -150,30 +339,131 @@ func parseMetrics(b []byte, contentType string, prefix string) []Metric {
 			n, t := p.Type()
 			cm.Name = string(n)
 			cm.Type = t
+			family = cm.Name
 			continue
 		case textparse.EntryHelp:
 			n, h := p.Help()
 			cm.Name = string(n)
 			cm.Help = string(h)
+			family = cm.Name
 			continue
+		case textparse.EntryUnit:
+			n, u := p.Unit()
+			cm.Name = string(n)
+			cm.Unit = string(u)
+			family = cm.Name
+			continue
+		case textparse.EntrySeries:
 		default:
+			continue
 		}
 
-		if cm.Name != "" {
-			var lset labels.Labels
-			p.Metric(&lset)
-			for _, l := range lset {
-				if l.Name == labels.MetricName {
-					continue
+		var lset labels.Labels
+		p.Metric(&lset)
+		seriesName := lset.Get(labels.MetricName)
+		if cm.Name == "" {
+			if belongsToFamily(seriesName, family) {
+				// Another series of the metric read before.
+				if len(counters) > 0 {
+					counters[len(counters)-1].add(lset, nil)
 				}
 
-				cm.LabelKeys = append(cm.LabelKeys, l.Name)
+				continue
+			}
+
+			// A series without TYPE or HELP is untyped.
+			cm.Name = seriesName
+			family = seriesName
+		}
+
+		if cm.Type == "" {
+			cm.Type = textparse.MetricTypeUnknown
+		}
+
+		cm.Name = normalizeName(cm.Name, cm.Type, seriesName)
+		for _, l := range lset {
+			if l.Name == labels.MetricName {
+				continue
+			}
+
+			cm.LabelKeys = append(cm.LabelKeys, l.Name)
+		}
+		metrics = append(metrics, cm)
+		counter := newCardinalityCounter()
+		counter.add(lset, nil)
+		counters = append(counters, counter)
+		cm = Metric{}
+	}
+
+	for i, c := range counters {
+		c.apply(&metrics[i])
+	}
+
+	return dropCreatedMetrics(metrics)
+}
+
+// belongsToFamily reports whether a series is part of a metric family, e.g. "foo_bucket" is part of the histogram "foo".
+func belongsToFamily(seriesName, family string) bool {
+	if family == "" {
+		return false
+	}
+
+	if seriesName == family {
+		return true
+	}
+
+	for _, suffix := range familySuffixes {
+		if seriesName == family+suffix {
+			return true
+		}
+	}
+
+	return false
+}
+
+// normalizeName returns the name of the series to query for a metric family.
+// OpenMetrics names a counter "foo" but exposes its value as "foo_total". The same applies to info metrics and
+// "foo_info". Converters expect the name of the series.
+func normalizeName(family string, t textparse.MetricType, seriesName string) string {
+	if t == textparse.MetricTypeCounter && seriesName == family+"_total" {
+		return seriesName
+	}
+
+	if t == textparse.MetricTypeInfo && seriesName == family+"_info" {
+		return seriesName
+	}
+
+	return family
+}
+
+// dropCreatedMetrics removes metrics that expose the creation time of a counter, histogram or summary.
+// Some clients expose "foo_created" as a separate gauge in the Prometheus text format, which would result in a panel
+// that does not provide any insight.
+func dropCreatedMetrics(metrics []Metric) []Metric {
+	parents := map[string]struct{}{}
+	for _, m := range metrics {
+		switch m.Type {
+		case textparse.MetricTypeCounter, textparse.MetricTypeHistogram, textparse.MetricTypeSummary, textparse.MetricTypeGaugeHistogram:
+			parents[strings.TrimSuffix(m.Name, "_total")] = struct{}{}
+		}
+	}
+
+	result := []Metric{}
+	for _, m := range metrics {
+		if strings.HasSuffix(m.Name, "_created") {
+			if _, ok := parents[strings.TrimSuffix(m.Name, "_created")]; ok {
+				log.Debugf("dropping metric %s because it exposes the creation time of another metric", m.Name)
+				continue
 			}
-			metrics = append(metrics, cm)
-			cm = Metric{}
 		}
+
+		result = append(result, m)
 	}
 
+	return result
+}
+
+func filterMetrics(metrics []Metric, prefix string) []Metric {
 	result := []Metric{}
 	for _, m := range metrics {
 		if strings.HasPrefix(m.Name, prefix) {
@@ -198,7 +488,14 @@ func groupMetrics(metrics []Metric, level int) Groups {
 	groups := Groups{
 		groupNameGeneral: {},
 	}
+	explicit := map[string]bool{}
 	for _, m := range metrics {
+		if m.Group != "" {
+			groups[m.Group] = append(groups[m.Group], m)
+			explicit[m.Group] = true
+			continue
+		}
+
 		parts := strings.Split(m.Name, "_")
 		if len(parts) <= level || level == 0 {
 			groups[groupNameGeneral] = append(groups[groupNameGeneral], m)
@@ -215,7 +512,7 @@ func groupMetrics(metrics []Metric, level int) Groups {
 	}
 
 	for n, ms := range groups {
-		if len(ms) < 2 {
+		if len(ms) < 2 && !explicit[n] {
 			groups[groupNameGeneral] = append(groups[groupNameGeneral], ms...)
 			delete(groups, n)
 		}
@@ -224,17 +521,20 @@ func groupMetrics(metrics []Metric, level int) Groups {
 	return groups
 }
 
-func queryFromPanels(panels []Panel) string {
-	for _, p := range panels {
-		switch p.Type() {
-		case PanelTypeGraph:
-			g := p.(Graph)
-			return g.Title
-		case PanelTypeSinglestat:
-			s := p.(Singlestat)
-			return s.Title
+// queryFromMetrics returns a series selector from which variables read the values of their labels.
+// It selects the series of the metric with the fewest labels because the labels of variables, e.g. "instance", are
+// attached to all series of a target. It selects "up" if there are no metrics.
+func queryFromMetrics(metrics []Metric) string {
+	if len(metrics) == 0 {
+		return "up"
+	}
+
+	selected := metrics[0]
+	for _, m := range metrics[1:] {
+		if len(m.LabelKeys) < len(selected.LabelKeys) {
+			selected = m
 		}
 	}
 
-	return ""
+	return seriesName(selected)
 }
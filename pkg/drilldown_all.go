@@ -0,0 +1,122 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	pav1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	log "github.com/sirupsen/logrus"
+	"github.com/wndhydrnt/autoboard/pkg/config"
+)
+
+// JobTarget is the target picked to read the metrics of a job.
+type JobTarget struct {
+	Job       string
+	ScrapeURL string
+}
+
+// RunAll creates one drilldown dashboard per job of the active targets in Prometheus.
+// The metrics of a job are read from the first healthy target of the job.
+// If useMetadata is true, the metrics are read from the metadata API of Prometheus instead.
+// Only jobs that match at least one of the filters are processed. All jobs are processed if filters is empty.
+// A job that fails does not stop the dashboards of the other jobs. The errors of all failed jobs are returned at the end.
+func (d *Drilldown) RunAll(cfg config.Config, counterChangeFunc string, useMetadata bool, filters []*regexp.Regexp, groupLevel int, labels []string, titlePrefix, prefix, timeRange string) error {
+	log.SetLevel(cfg.LogLevel)
+	api, err := newPrometheusAPIFromConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	targets, err := api.Targets(context.Background())
+	if err != nil {
+		return fmt.Errorf("read targets from Prometheus: %w", err)
+	}
+
+	failed := []string{}
+	for _, jt := range pickJobTargets(targets.Active, filters, !useMetadata) {
+		err := d.runJob(cfg, api, jt, counterChangeFunc, useMetadata, groupLevel, labels, titlePrefix, prefix, timeRange)
+		if err != nil {
+			log.Errorf("create drilldown dashboard for job %s: %s", jt.Job, err)
+			failed = append(failed, fmt.Sprintf("job %s: %s", jt.Job, err))
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("create drilldown dashboards of %d jobs: %s", len(failed), strings.Join(failed, "; "))
+	}
+
+	return nil
+}
+
+// runJob creates the drilldown dashboard of one job.
+// Its variables read their values from the series "up" of the job. The Drilldown itself is not modified.
+func (d *Drilldown) runJob(cfg config.Config, api pav1.API, jt JobTarget, counterChangeFunc string, useMetadata bool, groupLevel int, labels []string, titlePrefix, prefix, timeRange string) error {
+	selector := fmt.Sprintf(`{%s=%q}`, model.JobLabel, jt.Job)
+	var source MetricSource
+	if useMetadata {
+		source = &MetadataSource{API: api, Selector: selector}
+	} else {
+		es, err := NewEndpointSource(cfg, jt.ScrapeURL)
+		if err != nil {
+			return err
+		}
+
+		source = es
+	}
+
+	jobDrilldown := *d
+	jobDrilldown.VariableQuery = "up" + selector
+	log.Infof("creating drilldown dashboard for job %s", jt.Job)
+	return jobDrilldown.Run(cfg, counterChangeFunc, source, groupLevel, labels, titlePrefix+jt.Job, prefix, timeRange)
+}
+
+// pickJobTargets groups targets by job and picks one target per job.
+// A healthy target is preferred. If requireHealthy is true, jobs without any healthy target are skipped.
+// Jobs are returned sorted by name.
+func pickJobTargets(targets []pav1.ActiveTarget, filters []*regexp.Regexp, requireHealthy bool) []JobTarget {
+	picked := map[string]pav1.ActiveTarget{}
+	for _, t := range targets {
+		job := string(t.Labels[model.JobLabel])
+		if job == "" || !matchesAny(job, filters) {
+			continue
+		}
+
+		current, exists := picked[job]
+		if !exists || (current.Health != pav1.HealthGood && t.Health == pav1.HealthGood) {
+			picked[job] = t
+		}
+	}
+
+	result := []JobTarget{}
+	for job, t := range picked {
+		if requireHealthy && t.Health != pav1.HealthGood {
+			log.Warnf("skipping job %s because none of its targets is healthy", job)
+			continue
+		}
+
+		result = append(result, JobTarget{Job: job, ScrapeURL: t.ScrapeURL})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Job < result[j].Job
+	})
+	return result
+}
+
+func matchesAny(s string, filters []*regexp.Regexp) bool {
+	if len(filters) == 0 {
+		return true
+	}
+
+	for _, f := range filters {
+		if f.MatchString(s) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,59 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	pav1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"github.com/wndhydrnt/autoboard/pkg/config"
+)
+
+func TestPickJobTargets(t *testing.T) {
+	targets := []pav1.ActiveTarget{
+		{Health: pav1.HealthBad, Labels: model.LabelSet{"job": "node"}, ScrapeURL: "http://node-1:9100/metrics"},
+		{Health: pav1.HealthGood, Labels: model.LabelSet{"job": "node"}, ScrapeURL: "http://node-2:9100/metrics"},
+		{Health: pav1.HealthBad, Labels: model.LabelSet{"job": "redis"}, ScrapeURL: "http://redis-exporter:9121/metrics"},
+		{Health: pav1.HealthGood, Labels: model.LabelSet{"job": "grafana"}, ScrapeURL: "http://grafana:3000/metrics"},
+	}
+
+	result := pickJobTargets(targets, nil, true)
+	require.Equal(t, []JobTarget{
+		{Job: "grafana", ScrapeURL: "http://grafana:3000/metrics"},
+		{Job: "node", ScrapeURL: "http://node-2:9100/metrics"},
+	}, result)
+
+	result = pickJobTargets(targets, []*regexp.Regexp{regexp.MustCompile("^redis$")}, false)
+	require.Equal(t, []JobTarget{
+		{Job: "redis", ScrapeURL: "http://redis-exporter:9121/metrics"},
+	}, result)
+}
+
+func TestDrilldown_RunAll_ContinuesAfterError(t *testing.T) {
+	var s *httptest.Server
+	s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/targets" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		fmt.Fprintf(w, `{"status":"success","data":{"activeTargets":[
+{"health":"up","labels":{"job":"api"},"scrapeUrl":"%[1]s/api/metrics"},
+{"health":"up","labels":{"job":"node"},"scrapeUrl":"%[1]s/node/metrics"}
+],"droppedTargets":[]}}`, s.URL)
+	}))
+	defer s.Close()
+
+	d := NewDrilldown()
+	err := d.RunAll(config.Config{PrometheusAddress: s.URL}, "rate", false, nil, 0, []string{"instance"}, "", "", "5m")
+
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "create drilldown dashboards of 2 jobs")
+	require.Contains(t, err.Error(), "job api:")
+	require.Contains(t, err.Error(), "job node:")
+	require.Empty(t, d.VariableQuery)
+}
@@ -0,0 +1,258 @@
+package v1
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/prometheus/prometheus/pkg/textparse"
+	"github.com/stretchr/testify/require"
+	"github.com/wndhydrnt/autoboard/pkg/config"
+)
+
+const openMetricsInput = `# HELP requests Number of requests.
+# TYPE requests counter
+requests_total{code="200"} 10
+requests_created{code="200"} 1.6e+09
+# TYPE build info
+build_info{version="1.0.0"} 1
+# TYPE state stateset
+state{state="running"} 1
+state{state="stopped"} 0
+# TYPE queue_size gaugehistogram
+queue_size_bucket{le="10"} 3
+queue_size_bucket{le="+Inf"} 5
+queue_size_gcount 5
+queue_size_gsum 40
+# TYPE temperature unknown
+temperature{room="kitchen"} 21
+# EOF
+`
+
+const textInput = `# HELP jobs_total Number of jobs.
+# TYPE jobs_total counter
+jobs_total 3
+# HELP jobs_created Time when jobs_total was created.
+# TYPE jobs_created gauge
+jobs_created 1.6e+09
+legacy_value{kind="a"} 1
+legacy_value{kind="b"} 2
+`
+
+func TestParseMetrics_OpenMetrics(t *testing.T) {
+	metrics := parseMetrics([]byte(openMetricsInput), contentTypeOpenMetrics)
+	require.Equal(t, []Metric{
+		{Help: "Number of requests.", LabelKeys: []string{"code"}, LabelValues: map[string]int{"code": 1}, Name: "requests_total", Series: 1, Type: textparse.MetricTypeCounter},
+		{LabelKeys: []string{"version"}, LabelValues: map[string]int{"version": 1}, Name: "build_info", Series: 1, Type: textparse.MetricTypeInfo},
+		{LabelKeys: []string{"state"}, LabelValues: map[string]int{"state": 2}, Name: "state", Series: 2, Type: textparse.MetricTypeStateset},
+		{LabelKeys: []string{"le"}, Name: "queue_size", Series: 1, Type: textparse.MetricTypeGaugeHistogram},
+		{LabelKeys: []string{"room"}, LabelValues: map[string]int{"room": 1}, Name: "temperature", Series: 1, Type: textparse.MetricTypeUnknown},
+	}, metrics)
+}
+
+func TestParseMetrics_Text(t *testing.T) {
+	metrics := parseMetrics([]byte(textInput), contentTypeText)
+	require.Equal(t, []Metric{
+		{Help: "Number of jobs.", Name: "jobs_total", Series: 1, Type: textparse.MetricTypeCounter},
+		{LabelKeys: []string{"kind"}, LabelValues: map[string]int{"kind": 2}, Name: "legacy_value", Series: 2, Type: textparse.MetricTypeUnknown},
+	}, metrics)
+}
+
+func TestNewDrilldown_ConvertsOpenMetricsTypes(t *testing.T) {
+	d := NewDrilldown()
+	for _, m := range parseMetrics([]byte(openMetricsInput), contentTypeOpenMetrics) {
+		c := findConverter(m, d.Converters)
+		require.NotNil(t, c, "no converter for %s", m.Name)
+		require.NotEmpty(t, c.Do(m, Options{CounterChangeFunc: "rate", TimeRange: "5m"}))
+	}
+}
+
+func TestHistogramConverter_Heatmap(t *testing.T) {
+	m := Metric{
+		LabelKeys: []string{"handler", "le"},
+		Name:      "http_request_duration_seconds",
+		Type:      textparse.MetricTypeHistogram,
+	}
+	hc := &HistogramConverter{}
+
+	panels := hc.Do(m, Options{Labels: []string{"instance"}, TimeRange: "5m"})
+	require.Len(t, panels, 4)
+
+	panels = hc.Do(m, Options{Heatmap: HeatmapModeAdd, Labels: []string{"instance"}, TimeRange: "5m"})
+	require.Len(t, panels, 5)
+	require.Equal(t, Heatmap{
+		Format: "s",
+		Legend: "{{le}}",
+		Query:  `sum by (le) (rate(http_request_duration_seconds_bucket{instance=~"$instance"}[5m]))`,
+		Title:  "http_request_duration_seconds heatmap",
+	}, panels[4])
+
+	panels = hc.Do(m, Options{Heatmap: HeatmapModeReplace, Labels: []string{"instance"}, TimeRange: "5m"})
+	require.Len(t, panels, 2)
+	require.Equal(t, "http_request_duration_seconds avg", panels[0].(Graph).Title)
+	require.Equal(t, PanelTypeHeatmap, panels[1].Type())
+}
+
+func TestRenderer_Heatmap(t *testing.T) {
+	cfg, err := config.Parse("../test/config.yml")
+	require.NoError(t, err)
+	r := newRenderer(cfg)
+	panels := (&HistogramConverter{}).Do(Metric{
+		Name: "http_request_duration_seconds",
+		Type: textparse.MetricTypeHistogram,
+	}, Options{Heatmap: HeatmapModeReplace, Labels: []string{"instance"}, TimeRange: "5m"})
+
+	out, err := r.Render(Dashboard{Title: "Heatmap"}, panels)
+	require.NoError(t, err)
+	var db map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(out), &db))
+	rendered := db["panels"].([]interface{})
+	require.Len(t, rendered, 2)
+	heatmap := rendered[1].(map[string]interface{})
+	require.Equal(t, "heatmap", heatmap["type"])
+	target := heatmap["targets"].([]interface{})[0].(map[string]interface{})
+	require.Equal(t, "heatmap", target["format"])
+	require.Equal(t, `sum by (le) (rate(http_request_duration_seconds_bucket{instance=~"$instance"}[5m]))`, target["expr"])
+}
+
+func TestHistogramConverter_Quantiles(t *testing.T) {
+	m := Metric{
+		LabelKeys: []string{"handler", "le", "method"},
+		Name:      "http_request_duration_seconds",
+		Type:      textparse.MetricTypeHistogram,
+	}
+	hc := &HistogramConverter{}
+
+	panels := hc.Do(m, Options{TimeRange: "5m"})
+	require.Len(t, panels, 4)
+	avg := panels[0].(Graph)
+	require.Equal(t, "sum by (handler, method) (rate(http_request_duration_seconds_sum[5m])) / sum by (handler, method) (rate(http_request_duration_seconds_count[5m]))", avg.Queries[0].Query)
+	p50 := panels[1].(Graph)
+	require.Equal(t, "http_request_duration_seconds p50", p50.Title)
+	require.Equal(t, "histogram_quantile(0.5, sum by (le, handler, method) (rate(http_request_duration_seconds_bucket[5m])))", p50.Queries[0].Query)
+	require.Equal(t, "{{handler}} {{method}}", p50.Legend)
+
+	panels = hc.Do(m, Options{HistogramBy: []string{"handler"}, Quantiles: []float64{0.95, 0.999}, TimeRange: "5m"})
+	require.Len(t, panels, 3)
+	require.Equal(t, "sum by (handler) (rate(http_request_duration_seconds_sum[5m])) / sum by (handler) (rate(http_request_duration_seconds_count[5m]))", panels[0].(Graph).Queries[0].Query)
+	require.Equal(t, "http_request_duration_seconds p95", panels[1].(Graph).Title)
+	require.Equal(t, "http_request_duration_seconds p99.9", panels[2].(Graph).Title)
+	require.Equal(t, "histogram_quantile(0.999, sum by (le, handler) (rate(http_request_duration_seconds_bucket[5m])))", panels[2].(Graph).Queries[0].Query)
+
+	panels = hc.Do(m, Options{CombineQuantiles: true, HistogramBy: []string{"handler"}, TimeRange: "5m"})
+	require.Len(t, panels, 2)
+	combined := panels[1].(Graph)
+	require.Equal(t, "http_request_duration_seconds quantiles", combined.Title)
+	require.Len(t, combined.Queries, 3)
+	require.Equal(t, "p90 {{handler}}", combined.Queries[1].Legend)
+	require.Equal(t, "histogram_quantile(0.9, sum by (le, handler) (rate(http_request_duration_seconds_bucket[5m])))", combined.Queries[1].Query)
+}
+
+func TestSummaryConverter_Do(t *testing.T) {
+	m := Metric{
+		LabelKeys: []string{"handler", "quantile"},
+		Name:      "rpc_duration_seconds",
+		Type:      textparse.MetricTypeSummary,
+	}
+	sc := &SummaryConverter{}
+
+	panels := sc.Do(m, Options{CounterChangeFunc: "rate", Labels: []string{"instance"}, TimeRange: "5m"})
+	require.Len(t, panels, 3)
+	quantiles := panels[0].(Graph)
+	require.Equal(t, "rpc_duration_seconds quantiles", quantiles.Title)
+	require.Equal(t, `rpc_duration_seconds{instance=~"$instance"}`, quantiles.Queries[0].Query)
+	require.Equal(t, "{{quantile}} {{handler}}", quantiles.Legend)
+	require.True(t, quantiles.HasLegend)
+	avg := panels[1].(Graph)
+	require.Equal(t, "rpc_duration_seconds avg", avg.Title)
+	require.Equal(t, `rate(rpc_duration_seconds_sum{instance=~"$instance"}[5m]) / rate(rpc_duration_seconds_count{instance=~"$instance"}[5m])`, avg.Queries[0].Query)
+	require.Equal(t, "{{handler}}", avg.Legend)
+	require.True(t, avg.HasLegend)
+	count := panels[2].(Graph)
+	require.Equal(t, "rpc_duration_seconds_count rate over 5m", count.Title)
+	require.Equal(t, `rate(rpc_duration_seconds_count{instance=~"$instance"}[5m])`, count.Queries[0].Query)
+	require.Equal(t, "{{handler}}", count.Legend)
+	require.True(t, count.HasLegend)
+
+	m.LabelKeys = []string{"quantile"}
+	panels = sc.Do(m, Options{CounterChangeFunc: "rate", TimeRange: "5m"})
+	require.Len(t, panels, 3)
+	require.Equal(t, "{{quantile}} {{instance}}", panels[0].(Graph).Legend)
+	require.True(t, panels[0].(Graph).HasLegend)
+	require.Equal(t, "rate(rpc_duration_seconds_sum[5m]) / rate(rpc_duration_seconds_count[5m])", panels[1].(Graph).Queries[0].Query)
+	for _, p := range panels[1:] {
+		require.Equal(t, "{{instance}}", p.(Graph).Legend)
+		require.False(t, p.(Graph).HasLegend)
+	}
+}
+
+func TestCollapseRows(t *testing.T) {
+	panels := []Panel{
+		Row{Title: "General"},
+		Graph{Title: "general_1"},
+		Graph{Title: "general_2"},
+		Row{Title: "go"},
+		Graph{Title: "go_1"},
+		Row{Title: "process"},
+		Graph{Title: "process_1"},
+		Graph{Title: "process_2"},
+		Graph{Title: "process_3"},
+	}
+
+	collapsed := func(panels []Panel) []bool {
+		result := []bool{}
+		for _, p := range panels {
+			if row, ok := p.(Row); ok {
+				result = append(result, row.Collapsed)
+			}
+		}
+
+		return result
+	}
+
+	require.Equal(t, []bool{false, false, false}, collapsed(collapseRows(panels, false, 0)))
+	require.Equal(t, []bool{false, true, true}, collapsed(collapseRows(panels, true, 0)))
+	require.Equal(t, []bool{false, false, true}, collapsed(collapseRows(panels, false, 2)))
+	require.Equal(t, []bool{true, false, true}, collapsed(collapseRows(panels, false, 1)))
+	require.Equal(t, []bool{false, false, false}, collapsed(panels), "does not modify the original panels")
+}
+
+func TestDrilldown_RepeatBy(t *testing.T) {
+	metrics := []Metric{
+		{Name: "http_requests_total", LabelKeys: []string{"code", "handler"}, Type: textparse.MetricTypeCounter},
+		{Name: "http_request_duration_seconds", LabelKeys: []string{"handler", "le"}, Type: textparse.MetricTypeHistogram},
+		{Name: "process_open_fds", Type: textparse.MetricTypeGauge},
+	}
+
+	with, without := splitMetricsByLabel(metrics, "handler")
+	require.Equal(t, metrics[:2], with)
+	require.Equal(t, metrics[2:], without)
+	require.Equal(t, "http_request_duration_seconds_bucket", seriesName(with[1]))
+	require.Equal(t, "http_requests_total", seriesName(with[0]))
+
+	panels := repeatRows([]Panel{Row{Title: "http"}, Graph{Title: "http_requests_total"}}, "handler")
+	require.Equal(t, Row{Repeat: "handler", Title: "http $handler"}, panels[0])
+	require.Equal(t, Graph{Title: "http_requests_total"}, panels[1])
+}
+
+func TestDrilldown_Run_RepeatBySelector(t *testing.T) {
+	cfg, err := config.Parse("../test/config.yml")
+	require.NoError(t, err)
+	d := NewDrilldown()
+	d.RepeatBy = "instance"
+
+	err = d.Run(cfg, "rate", &MultiSource{}, 0, []string{"job", "instance"}, "Repeat", "", "5m")
+	require.EqualError(t, err, "repeat-by label instance is also a selector")
+}
+
+func TestQueryFromMetrics(t *testing.T) {
+	require.Equal(t, "up", queryFromMetrics(nil))
+	metrics := []Metric{
+		{Name: "http_request_duration_seconds", LabelKeys: []string{"handler", "le"}, Type: textparse.MetricTypeHistogram},
+		{Name: "go_gc_duration_seconds", LabelKeys: []string{"quantile"}, Type: textparse.MetricTypeSummary},
+		{Name: "http_requests_total", LabelKeys: []string{"code"}, Type: textparse.MetricTypeCounter},
+	}
+	require.Equal(t, "go_gc_duration_seconds_count", queryFromMetrics(metrics))
+
+	metrics = append(metrics, Metric{Name: "process_start_time_seconds", Type: textparse.MetricTypeGauge})
+	require.Equal(t, "process_start_time_seconds", queryFromMetrics(metrics))
+}
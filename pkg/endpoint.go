@@ -0,0 +1,135 @@
+package v1
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/wndhydrnt/autoboard/pkg/config"
+)
+
+const (
+	contentTypeOpenMetrics = "application/openmetrics-text; version=0.0.1; charset=utf-8"
+	contentTypeText        = "text/plain; version=0.0.4; charset=utf-8"
+	// Same Accept header as sent by Prometheus when it scrapes a target.
+	scrapeAcceptHeader = "application/openmetrics-text; version=0.0.1,text/plain;version=0.0.4;q=0.5,*/*;q=0.1"
+	stdinEndpoint      = "-"
+)
+
+// EndpointSource reads Metrics from the endpoint at which a service exposes them.
+type EndpointSource struct {
+	Client   *http.Client
+	Endpoint string
+	// Stdin is read if Endpoint is "-".
+	Stdin io.Reader
+}
+
+// NewEndpointSource returns an EndpointSource that connects to the endpoint as configured by the drilldown settings.
+func NewEndpointSource(cfg config.Config, endpoint string) (*EndpointSource, error) {
+	rt, err := newRoundTripper(cfg.DrilldownHTTP, "")
+	if err != nil {
+		return nil, fmt.Errorf("init HTTP client: %w", err)
+	}
+
+	return &EndpointSource{
+		Client: &http.Client{
+			Timeout:   cfg.DrilldownScrapeTimeout,
+			Transport: rt,
+		},
+		Endpoint: endpoint,
+		Stdin:    os.Stdin,
+	}, nil
+}
+
+// Metrics implements MetricSource.
+func (e *EndpointSource) Metrics() ([]Metric, error) {
+	b, contentType, err := readEndpoint(e.Client, e.Stdin, e.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseMetrics(b, contentType), nil
+}
+
+// String implements fmt.Stringer.
+func (e *EndpointSource) String() string {
+	return e.Endpoint
+}
+
+// readEndpoint reads the metrics exposed at an endpoint and returns them together with their content type.
+// The endpoint can be an HTTP(S) URL, a file:// URL, a path to a file or "-" to read from stdin.
+func readEndpoint(c *http.Client, stdin io.Reader, endpoint string) ([]byte, string, error) {
+	if endpoint == stdinEndpoint {
+		b, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			return nil, "", fmt.Errorf("reading metrics from stdin: %w", err)
+		}
+
+		return b, detectContentType(b, ""), nil
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "file") {
+		// Not a URL. Treat it as a path to a file.
+		u = &url.URL{Scheme: "file", Path: endpoint}
+	}
+
+	if u.Scheme == "file" {
+		path := u.Path
+		if path == "" {
+			// Relative paths, e.g. "file:metrics.txt", end up in Opaque.
+			path = u.Opaque
+		}
+
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, "", fmt.Errorf("reading metrics from file %s: %w", path, err)
+		}
+
+		return b, detectContentType(b, ""), nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("reading Prometheus endpoint %s: %w", endpoint, err)
+	}
+
+	req.Header.Set("Accept", scrapeAcceptHeader)
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("reading Prometheus endpoint %s: %w", endpoint, err)
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return nil, "", fmt.Errorf("reading Prometheus endpoint %s: status code %d", endpoint, resp.StatusCode)
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("reading body of Prometheus endpoint %s: %w", endpoint, err)
+	}
+
+	return b, detectContentType(b, resp.Header.Get("Content-Type")), nil
+}
+
+// detectContentType returns the content type of metrics in the Prometheus text format or in OpenMetrics.
+// A content type sent by the server takes precedence.
+// Without it, the content is considered OpenMetrics if it ends with the "# EOF" line that OpenMetrics requires.
+func detectContentType(b []byte, header string) string {
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err == nil && (mediaType == "application/openmetrics-text" || mediaType == "text/plain") {
+		return header
+	}
+
+	if bytes.HasSuffix(bytes.TrimRight(b, "\r\n"), []byte("# EOF")) {
+		return contentTypeOpenMetrics
+	}
+
+	return contentTypeText
+}
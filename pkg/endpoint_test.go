@@ -0,0 +1,77 @@
+package v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/wndhydrnt/autoboard/pkg/config"
+)
+
+func TestReadEndpoint(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Contains(t, r.Header.Get("Accept"), "application/openmetrics-text")
+		w.Header().Set("Content-Type", contentTypeText)
+		w.Write([]byte("up 1\n"))
+	}))
+	defer s.Close()
+
+	testCases := []struct {
+		name                string
+		endpoint            string
+		stdin               string
+		expectedContentType string
+	}{
+		{name: "http", endpoint: s.URL, expectedContentType: contentTypeText},
+		{name: "path", endpoint: "../test/metrics.txt", expectedContentType: contentTypeText},
+		{name: "file URL", endpoint: "file:../test/metrics.txt", expectedContentType: contentTypeText},
+		{name: "stdin text", endpoint: "-", stdin: "up 1\n", expectedContentType: contentTypeText},
+		{name: "stdin OpenMetrics", endpoint: "-", stdin: "up 1\n# EOF\n", expectedContentType: contentTypeOpenMetrics},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			b, contentType, err := readEndpoint(http.DefaultClient, strings.NewReader(tc.stdin), tc.endpoint)
+			require.NoError(t, err)
+			require.NotEmpty(t, b)
+			require.Equal(t, tc.expectedContentType, contentType)
+		})
+	}
+}
+
+func TestEndpointSource_ScrapeTimeout(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(5 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer s.Close()
+
+	source, err := NewEndpointSource(config.Config{DrilldownScrapeTimeout: 50 * time.Millisecond}, s.URL)
+	require.NoError(t, err)
+	_, err = source.Metrics()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Client.Timeout exceeded")
+}
+
+func TestEndpointSource_ProxyURL(t *testing.T) {
+	var requested string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.String()
+		w.Header().Set("Content-Type", contentTypeText)
+		w.Write([]byte("up 1\n"))
+	}))
+	defer proxy.Close()
+
+	cfg := config.Config{DrilldownHTTP: config.HTTPClientConfig{ProxyURL: proxy.URL}, DrilldownScrapeTimeout: 5 * time.Second}
+	source, err := NewEndpointSource(cfg, "http://my-service.invalid:8080/metrics")
+	require.NoError(t, err)
+	metrics, err := source.Metrics()
+	require.NoError(t, err)
+	require.Equal(t, "http://my-service.invalid:8080/metrics", requested)
+	require.Equal(t, "up", metrics[0].Name)
+}
@@ -1,68 +1,182 @@
 package v1
 
-import "strings"
-
-type FormatMapper struct {
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/prometheus/prometheus/pkg/textparse"
+	"github.com/wndhydrnt/autoboard/pkg/config"
+)
+
+const (
+	// FunctionNone matches the value of a metric without any PromQL function applied to it.
+	FunctionNone = "none"
+)
+
+// A FormatRule maps a metric to a format of Grafana.
+// Every condition of a rule is optional. A rule without any condition matches every metric.
+type FormatRule struct {
+	// Format is the format of Grafana, e.g. "s" or "decbytes".
+	Format string
+	// Function matches the PromQL function applied to the metric, e.g. "rate".
+	// FunctionNone matches the value of the metric without any function applied to it.
+	Function *regexp.Regexp
+	// Name matches the name of the metric.
+	Name *regexp.Regexp
+	// Type matches the type of the metric.
+	Type textparse.MetricType
+	// Unit matches the unit declared by the metric via OpenMetrics.
+	Unit string
 }
 
-func (fm *FormatMapper) Find(metricName string) string {
-	if strings.HasSuffix(metricName, "_bytes") {
-		return "decbytes"
+func (r FormatRule) matches(metricName string, t textparse.MetricType, unit, function string) bool {
+	if r.Name != nil && !r.Name.MatchString(metricName) {
+		return false
 	}
 
-	if strings.HasSuffix(metricName, "_duration_seconds") {
-		return "s"
+	if r.Type != "" && r.Type != t {
+		return false
 	}
 
-	if strings.HasSuffix(metricName, "_interval_seconds") {
-		return "s"
+	if r.Unit != "" && r.Unit != unit {
+		return false
 	}
 
-	if strings.HasSuffix(metricName, "_range_seconds") {
-		return "s"
+	if function == "" {
+		function = FunctionNone
 	}
 
-	if strings.HasSuffix(metricName, "_cleanup_seconds") {
-		return "s"
+	if r.Function != nil && !r.Function.MatchString(function) {
+		return false
 	}
 
-	if strings.HasSuffix(metricName, "_in_seconds") {
-		return "s"
-	}
+	return true
+}
 
-	if strings.HasSuffix(metricName, "_seconds") {
-		return "dateTimeAsIso"
-	}
+var (
+	perSecondFunctions = regexp.MustCompile(`^(deriv|irate|rate)$`)
+	noFunction         = regexp.MustCompile(`^` + FunctionNone + `$`)
+)
+
+// DefaultFormatRules are evaluated after the rules configured by a user.
+// Rules for per-second changes come first, followed by rules for values of metrics.
+var DefaultFormatRules = []FormatRule{
+	{Function: perSecondFunctions, Unit: "bits", Format: "bps"},
+	{Function: perSecondFunctions, Unit: "bytes", Format: "Bps"},
+	{Function: perSecondFunctions, Unit: "joules", Format: "watt"},
+	// Seconds per second are dimensionless, e.g. the number of CPU cores used.
+	{Function: perSecondFunctions, Unit: "seconds", Format: "short"},
+	{Function: perSecondFunctions, Name: regexp.MustCompile(`_bytes(_total)?$`), Format: "Bps"},
+	{Function: perSecondFunctions, Name: regexp.MustCompile(`_requests_total$`), Format: "reqps"},
+	{Function: perSecondFunctions, Format: "short"},
+	// The value of a timestamp is a point in time, not a duration, even if its unit is "seconds".
+	{Function: noFunction, Name: regexp.MustCompile(`timestamp|_time(_seconds)?$`), Format: "dateTimeAsIso"},
+	{Unit: "amperes", Format: "amp"},
+	{Unit: "bits", Format: "bits"},
+	{Unit: "bytes", Format: "decbytes"},
+	{Unit: "celsius", Format: "celsius"},
+	{Unit: "fahrenheit", Format: "fahrenheit"},
+	{Unit: "grams", Format: "massg"},
+	{Unit: "hertz", Format: "hertz"},
+	{Unit: "joules", Format: "joule"},
+	{Unit: "kelvin", Format: "kelvin"},
+	{Unit: "meters", Format: "lengthm"},
+	{Unit: "percent", Format: "percent"},
+	{Unit: "ratio", Format: "percentunit"},
+	{Unit: "seconds", Format: "s"},
+	{Unit: "volts", Format: "volt"},
+	{Unit: "watts", Format: "watt"},
+	{Name: regexp.MustCompile(`_bytes(_total)?$`), Format: "decbytes"},
+	{Name: regexp.MustCompile(`_seconds$`), Format: "s"},
+}
 
-	if strings.HasSuffix(metricName, "_timestamp") {
-		return "dateTimeAsIso"
-	}
+// FormatMapper finds the format of a panel in Grafana for a metric.
+// It evaluates its rules in order. The format of the first rule that matches is used.
+type FormatMapper struct {
+	Rules []FormatRule
+}
 
-	if strings.HasSuffix(metricName, "_time") {
-		return "dateTimeAsIso"
+// NewFormatMapper returns a FormatMapper that evaluates the rules configured by a user before DefaultFormatRules.
+func NewFormatMapper(rules []config.FormatRule) (*FormatMapper, error) {
+	fm := &FormatMapper{}
+	for i, r := range rules {
+		rule := FormatRule{
+			Format: r.Format,
+			Type:   textparse.MetricType(r.Type),
+			Unit:   r.Unit,
+		}
+		if r.Format == "" {
+			return nil, fmt.Errorf("format rule %d: format is empty", i+1)
+		}
+
+		err := r.Validate()
+		if err != nil {
+			return nil, fmt.Errorf("format rule %d: %w", i+1, err)
+		}
+
+		if r.Function != "" {
+			re, err := regexp.Compile("^(" + r.Function + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("format rule %d: compile function: %w", i+1, err)
+			}
+
+			rule.Function = re
+		}
+
+		if r.Name != "" {
+			re, err := regexp.Compile(r.Name)
+			if err != nil {
+				return nil, fmt.Errorf("format rule %d: compile name: %w", i+1, err)
+			}
+
+			rule.Name = re
+		}
+
+		fm.Rules = append(fm.Rules, rule)
 	}
 
-	return "short"
+	fm.Rules = append(fm.Rules, DefaultFormatRules...)
+	return fm, nil
 }
 
-func (fm *FormatMapper) FindRange(metricName string) string {
-	if strings.HasSuffix(metricName, "_bytes_total") || strings.HasSuffix(metricName, "_bytes") {
-		return "Bps"
+// Find returns the format of a panel that applies function to a metric.
+// function is empty if the panel displays the value of the metric.
+func (fm *FormatMapper) Find(metricName string, t textparse.MetricType, unit, function string) string {
+	for _, r := range fm.Rules {
+		if r.matches(metricName, t, unit, function) {
+			return r.Format
+		}
 	}
 
-	if strings.HasSuffix(metricName, "_requests_total") {
-		return "reqps"
-	}
+	return defaultFormat
+}
+
+// DefaultFormatMapper evaluates DefaultFormatRules only.
+var DefaultFormatMapper = &FormatMapper{Rules: DefaultFormatRules}
 
-	return "short"
+// FindFormat returns the format of a panel that displays the value of a metric.
+// It uses DefaultFormatMapper and thus ignores the rules configured by a user.
+func FindFormat(m Metric) string {
+	return DefaultFormatMapper.Find(m.Name, m.Type, m.Unit, "")
 }
 
-var DefaultFormatMapper = FormatMapper{}
+// FindFunctionFormat returns the format of a panel that applies a PromQL function to a metric, e.g. rate().
+// It uses DefaultFormatMapper and thus ignores the rules configured by a user.
+func FindFunctionFormat(m Metric, function string) string {
+	return DefaultFormatMapper.Find(m.Name, m.Type, m.Unit, function)
+}
 
-func FindFormat(metricName string) string {
-	return DefaultFormatMapper.Find(metricName)
+// findFormat returns the format of a panel that displays the value of a metric.
+func (o Options) findFormat(m Metric) string {
+	return o.findFunctionFormat(m, "")
 }
 
-func FindRangeFormat(metricName string) string {
-	return DefaultFormatMapper.FindRange(metricName)
+// findFunctionFormat returns the format of a panel that applies a PromQL function to a metric.
+func (o Options) findFunctionFormat(m Metric, function string) string {
+	fm := o.FormatMapper
+	if fm == nil {
+		fm = DefaultFormatMapper
+	}
+
+	return fm.Find(m.Name, m.Type, m.Unit, function)
 }
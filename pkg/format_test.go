@@ -0,0 +1,88 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/prometheus/prometheus/pkg/textparse"
+	"github.com/stretchr/testify/require"
+	"github.com/wndhydrnt/autoboard/pkg/config"
+)
+
+func TestFormatMapper_Find(t *testing.T) {
+	fm := &FormatMapper{Rules: DefaultFormatRules}
+	testCases := []struct {
+		metricName string
+		unit       string
+		function   string
+		expected   string
+	}{
+		{metricName: "node_hwmon_temp", unit: "celsius", expected: "celsius"},
+		{metricName: "cache_hit", unit: "ratio", expected: "percentunit"},
+		{metricName: "process_start_time_seconds", unit: "", expected: "dateTimeAsIso"},
+		{metricName: "process_start_time_seconds", unit: "seconds", expected: "dateTimeAsIso"},
+		{metricName: "last_success_timestamp_seconds", unit: "seconds", expected: "dateTimeAsIso"},
+		{metricName: "go_gc_pause_seconds", unit: "", expected: "s"},
+		{metricName: "memory_bytes", unit: "unknown_unit", expected: "decbytes"},
+		{metricName: "jobs", unit: "", expected: "short"},
+		{metricName: "energy_joules_total", unit: "joules", function: "rate", expected: "watt"},
+		{metricName: "received_bytes_total", unit: "", function: "irate", expected: "Bps"},
+		{metricName: "received_bytes_total", unit: "", function: "increase", expected: "decbytes"},
+		{metricName: "memory_bytes", unit: "", function: "deriv", expected: "Bps"},
+		{metricName: "jobs_total", unit: "", function: "rate", expected: "short"},
+		{metricName: "process_cpu_seconds_total", unit: "seconds", function: "rate", expected: "short"},
+		{metricName: "process_cpu_seconds_total", unit: "seconds", function: "increase", expected: "s"},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.expected, fm.Find(tc.metricName, textparse.MetricTypeUnknown, tc.unit, tc.function), "%s (%s, %s)", tc.metricName, tc.unit, tc.function)
+	}
+}
+
+func TestNewFormatMapper(t *testing.T) {
+	fm, err := NewFormatMapper([]config.FormatRule{
+		{Name: "^myapp_.*_seconds$", Type: "gauge", Format: "ms"},
+		{Name: "_requests_total$", Function: "rate|irate", Format: "rps"},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, "ms", fm.Find("myapp_latency_seconds", textparse.MetricTypeGauge, "", ""))
+	require.Equal(t, "s", fm.Find("myapp_latency_seconds", textparse.MetricTypeCounter, "", ""))
+	require.Equal(t, "rps", fm.Find("http_requests_total", textparse.MetricTypeCounter, "", "rate"))
+	require.Equal(t, "short", fm.Find("http_requests_total", textparse.MetricTypeCounter, "", "increase"))
+}
+
+func TestOptions_FormatMapper(t *testing.T) {
+	fm, err := NewFormatMapper([]config.FormatRule{{Name: "^myapp_latency_seconds$", Format: "ms"}})
+	require.NoError(t, err)
+	m := Metric{Name: "myapp_latency_seconds", Type: textparse.MetricTypeGauge}
+
+	panels := (&GaugeConverter{}).Do(m, Options{FormatMapper: fm})
+	require.Equal(t, "ms", panels[0].(Singlestat).Format)
+
+	panels = (&GaugeConverter{}).Do(m, Options{})
+	require.Equal(t, "s", panels[0].(Singlestat).Format, "rules of other runs do not leak")
+}
+
+func TestNewFormatMapper_Errors(t *testing.T) {
+	_, err := NewFormatMapper([]config.FormatRule{{Name: "foo"}})
+	require.EqualError(t, err, "format rule 1: format is empty")
+
+	_, err = NewFormatMapper([]config.FormatRule{{Name: "(", Format: "s"}})
+	require.Error(t, err)
+
+	_, err = NewFormatMapper([]config.FormatRule{{Type: "counters", Format: "s"}})
+	require.EqualError(t, err, "format rule 1: unknown type counters, must be one of counter, gauge, gaugehistogram, histogram, info, stateset, summary, unknown")
+}
+
+func TestParseMetrics_Unit(t *testing.T) {
+	input := `# TYPE request_duration_seconds histogram
+# UNIT request_duration_seconds seconds
+request_duration_seconds_bucket{le="+Inf"} 1
+request_duration_seconds_sum 0.5
+request_duration_seconds_count 1
+# EOF
+`
+	metrics := parseMetrics([]byte(input), contentTypeOpenMetrics)
+	require.Len(t, metrics, 1)
+	require.Equal(t, "seconds", metrics[0].Unit)
+}
@@ -7,10 +7,12 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"reflect"
 	"strings"
 
 	"github.com/hoisie/mustache"
 	log "github.com/sirupsen/logrus"
+	"github.com/wndhydrnt/autoboard/pkg/config"
 )
 
 const (
@@ -53,6 +55,10 @@ func (g *Grafana) CreateDashboard(d string, folder string) error {
 		folderID = f.ID
 	}
 
+	if !json.Valid([]byte(d)) {
+		return fmt.Errorf("dashboard is not valid JSON")
+	}
+
 	dashboard := json.RawMessage([]byte(d))
 	request := &grafanaCreateDashboardRequest{
 		Dashboard: &dashboard,
@@ -139,76 +145,353 @@ func (g *Grafana) sendJSON(method string, url string, body io.Reader, data inter
 
 // Renderer contains all logic to create dashboard.
 type Renderer struct {
-	dashboardTpl         *mustache.Template
-	datasource           string
-	graphTpl             *mustache.Template
+	barGaugeTpl  *mustache.Template
+	dashboardTpl *mustache.Template
+	datasource   string
+	gaugeTpl     *mustache.Template
+	graphTpl     *mustache.Template
+	// gridColumns is the number of columns of the grid layout.
+	gridColumns int
+	heatmapTpl  *mustache.Template
+	// layout is the strategy that places panels on a dashboard.
+	layout string
+	// modern converts Graph and Singlestat panels to Timeseries and Stat panels.
+	modern               bool
 	panelHeight          int
 	panelWidthGraph      int
 	panelWidthSinglestat int
 	rowTpl               *mustache.Template
 	singlestatTpl        *mustache.Template
+	statTpl              *mustache.Template
+	tableTpl             *mustache.Template
+	timeseriesTpl        *mustache.Template
 }
 
-// Render takes templates, a Dashboard and a list of Panels and creates a JSON data model of dashboard as required by
-// Grafana.
-// Panels are placed on the dashboard in the order in which they are defined in the slice.
-func (r *Renderer) Render(db Dashboard, panels []Panel) string {
-	panelsRendered := []string{}
-	posX := 0
-	posY := 0
+// newRenderer returns a Renderer that uses the templates and the settings of panels in the Config.
+func newRenderer(cfg config.Config) *Renderer {
+	return &Renderer{
+		barGaugeTpl:          cfg.TemplateBarGauge,
+		dashboardTpl:         cfg.TemplateDashboard,
+		datasource:           cfg.Datasource,
+		gaugeTpl:             cfg.TemplateGauge,
+		graphTpl:             cfg.TemplateGraph,
+		gridColumns:          cfg.GrafanaPanelsGridColumns,
+		heatmapTpl:           cfg.TemplateHeatmap,
+		layout:               cfg.GrafanaPanelsLayout,
+		modern:               cfg.GrafanaPanelsStyle == config.PanelStyleModern,
+		panelHeight:          cfg.GrafanaPanelsHeight,
+		panelWidthGraph:      cfg.GrafanaPanelsGraphWidth,
+		panelWidthSinglestat: cfg.GrafanaPanelsSinglestatWidth,
+		rowTpl:               cfg.TemplateRow,
+		singlestatTpl:        cfg.TemplateSinglestat,
+		statTpl:              cfg.TemplateStat,
+		tableTpl:             cfg.TemplateTable,
+		timeseriesTpl:        cfg.TemplateTimeseries,
+	}
+}
+
+// Render takes a Dashboard and a list of Panels and creates a JSON data model of dashboard as required by Grafana.
+// The JSON of a panel or the dashboard is created from a template instead if one has been configured.
+// Panels are placed on the dashboard in the order in which they are defined in the slice, except for the compact
+// layout that moves panels which display a single value to the top of their row.
+func (r *Renderer) Render(db Dashboard, panels []Panel) (string, error) {
+	prepared := make([]Panel, 0, len(panels))
 	for _, p := range panels {
+		if r.modern {
+			switch v := p.(type) {
+			case Graph:
+				p = graphToTimeseries(prepareGraph(v))
+			case Singlestat:
+				p = singlestatToStat(v)
+			}
+		}
+
+		prepared = append(prepared, p)
+	}
+
+	if r.layout == config.LayoutCompact {
+		prepared = compactSinglestats(prepared)
+	}
+
+	positions := r.layoutPanels(prepared)
+	panelsRendered := []json.RawMessage{}
+	// collapsed is the index of the collapsed row in which panels are nested or -1 if panels are not nested.
+	collapsed := -1
+	nested := []json.RawMessage{}
+	// closeRow renders the current collapsed row with the panels nested in it.
+	closeRow := func() error {
+		if collapsed == -1 {
+			return nil
+		}
+
+		row := prepared[collapsed].(Row)
+		row.PosX, row.PosY = positions[collapsed].x, positions[collapsed].y
+		rendered, err := r.renderRow(row, nested)
+		if err != nil {
+			return fmt.Errorf("render %s panel %d: %w", row.Type(), collapsed+1, err)
+		}
+
+		panelsRendered = append(panelsRendered, rendered)
+		collapsed = -1
+		nested = []json.RawMessage{}
+		return nil
+	}
+
+	for i, p := range prepared {
+		pos := positions[i]
+		var rendered json.RawMessage
+		var err error
 		switch p.Type() {
 		case PanelTypeRow:
+			err = closeRow()
+			if err != nil {
+				return "", err
+			}
+
 			row := p.(Row)
-			row.PosX = 0
-			if posY != 0 {
-				posY = posY + r.panelHeight
+			if row.Collapsed {
+				collapsed = i
+				continue
 			}
 
-			row.PosY = posY
-			panelsRendered = append(panelsRendered, r.rowTpl.Render(row))
-			posX = 0
-			// A row always has a height of 1
-			posY = posY + 1
+			row.PosX, row.PosY = pos.x, pos.y
+			rendered, err = r.renderRow(row, nil)
 		case PanelTypeGraph:
-			graph := p.(Graph)
-			if (posX + r.panelWidthGraph) > 24 {
-				posX = 0
-				posY = posY + r.panelHeight
+			graph := prepareGraph(p.(Graph))
+			if graph.Datasource == "" {
+				graph.Datasource = r.datasource
+			}
+
+			graph.HasDatasource = graph.Datasource != ""
+			graph.Height = pos.height
+			graph.Width = pos.width
+			graph.PosX, graph.PosY = pos.x, pos.y
+			rendered, err = renderPanel(r.graphTpl, graph, newGraphModel(graph))
+		case PanelTypeHeatmap:
+			heatmap := p.(Heatmap)
+			if heatmap.Datasource == "" {
+				heatmap.Datasource = r.datasource
 			}
 
-			graph.Datasource = r.datasource
-			graph.HasDatasource = r.datasource != ""
-			graph.Height = r.panelHeight
-			graph.PosX = posX
-			graph.PosY = posY
-			graph.Width = r.panelWidthGraph
-			panelsRendered = append(panelsRendered, r.graphTpl.Render(graph))
-			posX = posX + r.panelWidthGraph
+			heatmap.HasDatasource = heatmap.Datasource != ""
+			heatmap.Height = pos.height
+			heatmap.Width = pos.width
+			heatmap.PosX, heatmap.PosY = pos.x, pos.y
+			rendered, err = renderPanel(r.heatmapTpl, heatmap, newHeatmapModel(heatmap))
 		case PanelTypeSinglestat:
 			singlestat := p.(Singlestat)
-			if (posX + r.panelWidthSinglestat) > 24 {
-				posX = 0
-				posY = posY + r.panelHeight
+			if singlestat.Datasource == "" {
+				singlestat.Datasource = r.datasource
+			}
+
+			singlestat.HasDatasource = singlestat.Datasource != ""
+			singlestat.Height = pos.height
+			singlestat.Width = pos.width
+			singlestat.PosX, singlestat.PosY = pos.x, pos.y
+			rendered, err = renderPanel(r.singlestatTpl, singlestat, newSinglestatModel(singlestat))
+		case PanelTypeTimeseries:
+			ts := p.(Timeseries)
+			if ts.Datasource == "" {
+				ts.Datasource = r.datasource
+			}
+
+			ts.FieldConfig = prepareFieldConfig(ts.FieldConfig)
+			ts.Queries = prepareGraph(Graph{Legend: ts.Legend, Queries: ts.Queries}).Queries
+			overrides := make([]TimeseriesOverride, len(ts.SeriesOverrides))
+			for i, o := range ts.SeriesOverrides {
+				o.HasMore = i+1 < len(ts.SeriesOverrides)
+				overrides[i] = o
 			}
 
-			singlestat.Datasource = r.datasource
-			singlestat.HasDatasource = r.datasource != ""
-			singlestat.Height = r.panelHeight
-			singlestat.PosX = posX
-			singlestat.PosY = posY
-			singlestat.Width = r.panelWidthSinglestat
-			panelsRendered = append(panelsRendered, r.singlestatTpl.Render(singlestat))
-			posX = posX + r.panelWidthSinglestat
+			ts.SeriesOverrides = overrides
+			ts.HasDatasource = ts.Datasource != ""
+			ts.Height = pos.height
+			ts.Width = pos.width
+			ts.PosX, ts.PosY = pos.x, pos.y
+			rendered, err = renderPanel(r.timeseriesTpl, ts, newTimeseriesModel(ts))
+		case PanelTypeStat:
+			stat := p.(Stat)
+			if stat.Datasource == "" {
+				stat.Datasource = r.datasource
+			}
+
+			if stat.Reduce == "" {
+				stat.Reduce = reduceCalc("")
+			}
+
+			stat.FieldConfig = prepareFieldConfig(stat.FieldConfig)
+			stat.HasDatasource = stat.Datasource != ""
+			stat.Height = pos.height
+			stat.Width = pos.width
+			stat.PosX, stat.PosY = pos.x, pos.y
+			rendered, err = renderPanel(r.statTpl, stat, newStatModel(stat))
+		case PanelTypeGauge:
+			gauge := p.(Gauge)
+			if gauge.Datasource == "" {
+				gauge.Datasource = r.datasource
+			}
+
+			if gauge.Reduce == "" {
+				gauge.Reduce = reduceCalc("")
+			}
+
+			gauge.FieldConfig = prepareFieldConfig(gauge.FieldConfig)
+			gauge.HasDatasource = gauge.Datasource != ""
+			gauge.Height = pos.height
+			gauge.Width = pos.width
+			gauge.PosX, gauge.PosY = pos.x, pos.y
+			rendered, err = renderPanel(r.gaugeTpl, gauge, newGaugeModel(gauge))
+		case PanelTypeBarGauge:
+			bg := p.(BarGauge)
+			if bg.Datasource == "" {
+				bg.Datasource = r.datasource
+			}
+
+			if bg.Reduce == "" {
+				bg.Reduce = reduceCalc("")
+			}
+
+			bg.FieldConfig = prepareFieldConfig(bg.FieldConfig)
+			bg.HasDatasource = bg.Datasource != ""
+			bg.Height = pos.height
+			bg.Width = pos.width
+			bg.PosX, bg.PosY = pos.x, pos.y
+			rendered, err = renderPanel(r.barGaugeTpl, bg, newBarGaugeModel(bg))
+		case PanelTypeTable:
+			table := p.(Table)
+			if table.Datasource == "" {
+				table.Datasource = r.datasource
+			}
+
+			table.FieldConfig = prepareFieldConfig(table.FieldConfig)
+			table.HasDatasource = table.Datasource != ""
+			table.Height = pos.height
+			table.Width = pos.width
+			table.PosX, table.PosY = pos.x, pos.y
+			rendered, err = renderPanel(r.tableTpl, table, newTableModel(table))
+		default:
+			continue
 		}
+
+		if err != nil {
+			return "", fmt.Errorf("render %s panel %d: %w", p.Type(), i+1, err)
+		}
+
+		if collapsed == -1 {
+			panelsRendered = append(panelsRendered, rendered)
+		} else {
+			nested = append(nested, rendered)
+		}
+	}
+
+	err := closeRow()
+	if err != nil {
+		return "", err
 	}
 
-	db.Panels = strings.Join(panelsRendered, ",")
-	return r.dashboardTpl.Render(db)
+	if r.dashboardTpl == nil {
+		b, err := json.Marshal(newDashboardModel(db, panelsRendered))
+		if err != nil {
+			return "", fmt.Errorf("render dashboard: %w", err)
+		}
+
+		return string(b), nil
+	}
+
+	data := escapeStrings(db).(Dashboard)
+	for i := range data.Variables {
+		data.Variables[i].HasMore = i+1 < len(data.Variables)
+	}
+
+	data.Panels = joinPanels(panelsRendered)
+	out := r.dashboardTpl.Render(data)
+	if !json.Valid([]byte(out)) {
+		return "", fmt.Errorf("render dashboard: template returned invalid JSON")
+	}
+
+	return out, nil
+}
+
+// renderRow renders a Row. The panels of a collapsed row are nested in it.
+func (r *Renderer) renderRow(row Row, panels []json.RawMessage) (json.RawMessage, error) {
+	if r.rowTpl == nil {
+		return json.Marshal(newRowModel(row, panels))
+	}
+
+	data := escapeStrings(row).(Row)
+	data.HasRepeat = data.Repeat != ""
+	data.Panels = joinPanels(panels)
+	out := r.rowTpl.Render(data)
+	if !json.Valid([]byte(out)) {
+		return nil, fmt.Errorf("template returned invalid JSON")
+	}
+
+	return json.RawMessage(out), nil
+}
+
+// joinPanels joins the JSON of panels to be embedded in a JSON array.
+func joinPanels(panels []json.RawMessage) string {
+	joined := []string{}
+	for _, p := range panels {
+		joined = append(joined, string(p))
+	}
+
+	return strings.Join(joined, ",")
+}
+
+// renderPanel marshals the model of a panel or, if a template is set, renders the template.
+// All strings of the panel are escaped before they are passed to the template.
+func renderPanel(tpl *mustache.Template, panel interface{}, model interface{}) (json.RawMessage, error) {
+	if tpl == nil {
+		return json.Marshal(model)
+	}
+
+	out := tpl.Render(escapeStrings(panel))
+	if !json.Valid([]byte(out)) {
+		return nil, fmt.Errorf("template returned invalid JSON")
+	}
+
+	return json.RawMessage(out), nil
+}
+
+// escapeStrings returns a copy of v in which all strings are escaped to be embedded in a JSON string.
+func escapeStrings(v interface{}) interface{} {
+	return escapeValue(reflect.ValueOf(v)).Interface()
+}
+
+func escapeValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.String:
+		b, _ := json.Marshal(v.String())
+		return reflect.ValueOf(string(b[1 : len(b)-1])).Convert(v.Type())
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.NumField(); i++ {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(escapeValue(v.Field(i)))
+			}
+		}
+
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(escapeValue(v.Index(i)))
+		}
+
+		return c
+	default:
+		return v
+	}
 }
 
 var (
 	PanelTypeGraph      = "graph"
+	PanelTypeHeatmap    = "heatmap"
 	PanelTypeRow        = "row"
 	PanelTypeSinglestat = "singlestat"
 )
@@ -229,17 +512,25 @@ type Dashboard struct {
 // Variable is rendered as a selector by Grafana.
 type Variable struct {
 	Datasource string
-	HasMore    bool
-	Query      string
-	Name       string
+	// HasMore is set by the Renderer.
+	HasMore bool
+	// IncludeAll adds the option "All" that selects all values.
+	IncludeAll bool
+	// Multi allows to select more than one value.
+	Multi bool
+	Query string
+	Name  string
 }
 
+// labelsToVariables returns a Variable for each label that reads its values from the series selected by query.
+// The variables are chained. Each one reads only the values of the series that match the previous variables,
+// e.g. instance reads label_values(up{job=~"$job"}, instance).
+// A variable allows to select more than one value or all values.
 func labelsToVariables(datasource string, labels []string, query string) []Variable {
 	variables := []Variable{}
 	for i, l := range labels {
-		v := Variable{Datasource: datasource, Name: l}
-		v.HasMore = i+1 < len(labels)
-		v.Query = fmt.Sprintf("label_values(%s, %s)", query, l)
+		v := Variable{Datasource: datasource, IncludeAll: true, Multi: true, Name: l}
+		v.Query = fmt.Sprintf("label_values(%s, %s)", addLabelMatchers(query, labels[:i]), l)
 		variables = append(variables, v)
 	}
 
@@ -249,10 +540,19 @@ func labelsToVariables(datasource string, labels []string, query string) []Varia
 // A Row is rendered as a row by Grafana.
 // Height and width are not configurable because a row in Grafana always has a height of "1" and a width of "24".
 type Row struct {
-	ID    int
-	PosX  int
-	PosY  int
-	Title string
+	// Collapsed hides the panels of the row until it is expanded in Grafana.
+	// The panels that follow a collapsed row, up to the next row, are nested in it.
+	Collapsed bool
+	// HasRepeat is set by the Renderer.
+	HasRepeat bool
+	ID        int
+	// Panels is set by the Renderer and contains the JSON of the nested panels.
+	Panels string
+	PosX   int
+	PosY   int
+	// Repeat is the name of a variable. Grafana repeats the row and its panels for every selected value of the variable.
+	Repeat string
+	Title  string
 }
 
 // Type implements Panel.
@@ -262,22 +562,24 @@ func (r Row) Type() string {
 
 // A Graph is rendered as a graph panel by Grafana.
 type Graph struct {
-	Datasource     string
-	Description    string
-	Format         string
-	HasDatasource  bool
-	HasLegend      bool
-	HasThreshold   bool
-	Height         int
-	ID             int
-	Legend         string
-	Queries        []GraphQuery
-	PosX           int
-	PosY           int
-	ThresholdOP    string
-	ThresholdValue string
-	Title          string
-	Width          int
+	Datasource    string
+	Description   string
+	Format        string
+	HasDatasource bool
+	HasLegend     bool
+	HasThreshold  bool
+	Height        int
+	ID            int
+	Legend        string
+	Queries       []GraphQuery
+	PosX          int
+	PosY          int
+	// SeriesOverrides change how single series are drawn, e.g. on the right y-axis.
+	SeriesOverrides []SeriesOverride
+	ThresholdOP     string
+	ThresholdValue  string
+	Title           string
+	Width           int
 }
 
 // Type implements Panel.
@@ -285,12 +587,84 @@ func (g Graph) Type() string {
 	return PanelTypeGraph
 }
 
+// A Heatmap is rendered as a heatmap panel by Grafana.
+// Query returns the buckets of a histogram.
+type Heatmap struct {
+	Datasource    string
+	Description   string
+	Format        string
+	HasDatasource bool
+	Height        int
+	ID            int
+	Legend        string
+	PosX          int
+	PosY          int
+	Query         string
+	Title         string
+	Width         int
+}
+
+// Type implements Panel.
+func (h Heatmap) Type() string {
+	return PanelTypeHeatmap
+}
+
 // A GraphQuery is rendered as one query in a graph.
+// The Renderer sets RefID to a unique letter if it is empty and Legend to the Legend of the Graph if it is empty.
 type GraphQuery struct {
 	Code    string
 	HasMore bool
-	Query   string
-	RefID   string
+	// Hide disables the display of the series returned by the query, e.g. if it is only referenced by other queries.
+	Hide   bool
+	Legend string
+	Query  string
+	RefID  string
+}
+
+// A SeriesOverride changes how the series that match Alias are drawn in a Graph.
+type SeriesOverride struct {
+	// Alias is the legend of the series to override. A value enclosed in "/" is a regular expression.
+	Alias   string
+	HasMore bool
+	// YAxis is the y-axis on which the series are drawn, 1 for left or 2 for right.
+	YAxis int
+}
+
+// prepareGraph sets the defaults of the queries and series overrides of a Graph.
+func prepareGraph(g Graph) Graph {
+	queries := make([]GraphQuery, len(g.Queries))
+	for i, q := range g.Queries {
+		if q.RefID == "" {
+			q.RefID = refID(i)
+		}
+
+		if q.Legend == "" {
+			q.Legend = g.Legend
+		}
+
+		q.HasMore = i+1 < len(g.Queries)
+		queries[i] = q
+	}
+
+	g.Queries = queries
+	overrides := make([]SeriesOverride, len(g.SeriesOverrides))
+	for i, so := range g.SeriesOverrides {
+		so.HasMore = i+1 < len(g.SeriesOverrides)
+		overrides[i] = so
+	}
+
+	g.SeriesOverrides = overrides
+	return g
+}
+
+// refID returns the identifier of the query at index i as assigned by Grafana, i.e. "A" to "Z", then "AA", "AB" etc.
+func refID(i int) string {
+	id := string(rune('A' + i%26))
+	for i = i/26 - 1; i >= 0; i = i/26 - 1 {
+		id = string(rune('A'+i%26)) + id
+	}
+
+	return id
 }
 
 // A Singlestat is rendered as a singlestat panel by Grafana.
@@ -0,0 +1,306 @@
+package v1
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hoisie/mustache"
+	"github.com/stretchr/testify/require"
+	"github.com/wndhydrnt/autoboard/pkg/config"
+)
+
+func TestRefID(t *testing.T) {
+	require.Equal(t, "A", refID(0))
+	require.Equal(t, "Z", refID(25))
+	require.Equal(t, "AA", refID(26))
+	require.Equal(t, "AB", refID(27))
+	require.Equal(t, "BA", refID(52))
+	require.Equal(t, "ZZ", refID(701))
+	require.Equal(t, "AAA", refID(702))
+}
+
+func TestRenderer_GraphQueries(t *testing.T) {
+	cfg, err := config.Parse("../test/config.yml")
+	require.NoError(t, err)
+	r := newRenderer(cfg)
+	g := Graph{
+		Legend: "{{instance}}",
+		Queries: []GraphQuery{
+			{Query: `rate(requests_total{code="500"}[5m])`, Hide: true},
+			{Query: `rate(requests_total[5m])`, Legend: "requests"},
+			{Query: `latency_seconds`, Legend: "latency", RefID: "L"},
+		},
+		SeriesOverrides: []SeriesOverride{{Alias: "latency", YAxis: 2}},
+		Title:           "Requests",
+	}
+
+	out, err := r.Render(Dashboard{Title: "Graph"}, []Panel{g})
+	require.NoError(t, err)
+	var db map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(out), &db))
+	panel := db["panels"].([]interface{})[0].(map[string]interface{})
+	targets := panel["targets"].([]interface{})
+	require.Len(t, targets, 3)
+
+	first := targets[0].(map[string]interface{})
+	require.Equal(t, "A", first["refId"])
+	require.Equal(t, "{{instance}}", first["legendFormat"])
+	require.Equal(t, true, first["hide"])
+	require.Equal(t, `rate(requests_total{code="500"}[5m])`, first["expr"])
+
+	second := targets[1].(map[string]interface{})
+	require.Equal(t, "B", second["refId"])
+	require.Equal(t, "requests", second["legendFormat"])
+	require.NotContains(t, second, "hide")
+
+	third := targets[2].(map[string]interface{})
+	require.Equal(t, "L", third["refId"])
+
+	require.Equal(t, []interface{}{
+		map[string]interface{}{"alias": "latency", "yaxis": float64(2)},
+	}, panel["seriesOverrides"])
+}
+
+func TestRenderer_Modern(t *testing.T) {
+	cfg, err := config.Parse("../test/config.yml")
+	require.NoError(t, err)
+	cfg.GrafanaPanelsStyle = config.PanelStyleModern
+	r := newRenderer(cfg)
+	panels := []Panel{
+		Row{Title: "Modern"},
+		Graph{
+			Format:          "s",
+			HasThreshold:    true,
+			Legend:          "{{handler}}",
+			Queries:         []GraphQuery{{Query: `rate(requests_total{code="500"}[5m])`}, {Query: "latency_seconds", Legend: "latency"}},
+			SeriesOverrides: []SeriesOverride{{Alias: "latency", YAxis: 2}},
+			ThresholdOP:     "gt",
+			ThresholdValue:  "0.5",
+			Title:           "Requests",
+		},
+		Singlestat{Format: "percentunit", Query: "avg(up)", ThresholdInvertYes: true, ThresholdValue: "1", Title: "Up", ValueName: "avg"},
+		Gauge{FieldConfig: FieldConfig{Format: "percentunit", Max: "1", Min: "0"}, Query: "avg(up)", Title: "Gauge"},
+		BarGauge{FieldConfig: FieldConfig{Mappings: []ValueMapping{{Text: "up", Value: "1"}}}, Query: "up", Title: "BarGauge"},
+		Table{Query: "up", Title: "Table"},
+	}
+
+	out, err := r.Render(Dashboard{Title: "Modern"}, panels)
+	require.NoError(t, err)
+	var db map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(out), &db), out)
+	rendered := db["panels"].([]interface{})
+	require.Len(t, rendered, 6)
+
+	ts := rendered[1].(map[string]interface{})
+	require.Equal(t, "timeseries", ts["type"])
+	defaults := ts["fieldConfig"].(map[string]interface{})["defaults"].(map[string]interface{})
+	require.Equal(t, "s", defaults["unit"])
+	require.Equal(t, map[string]interface{}{
+		"mode": "absolute",
+		"steps": []interface{}{
+			map[string]interface{}{"color": "transparent", "value": nil},
+			map[string]interface{}{"color": "red", "value": 0.5},
+		},
+	}, defaults["thresholds"])
+	targets := ts["targets"].([]interface{})
+	require.Len(t, targets, 2)
+	require.Equal(t, "B", targets[1].(map[string]interface{})["refId"])
+	overrides := ts["fieldConfig"].(map[string]interface{})["overrides"].([]interface{})
+	require.Len(t, overrides, 1)
+	require.Equal(t, map[string]interface{}{"id": "byName", "options": "latency"}, overrides[0].(map[string]interface{})["matcher"])
+
+	stat := rendered[2].(map[string]interface{})
+	require.Equal(t, "stat", stat["type"])
+	require.Equal(t, []interface{}{"mean"}, stat["options"].(map[string]interface{})["reduceOptions"].(map[string]interface{})["calcs"])
+
+	gauge := rendered[3].(map[string]interface{})
+	require.Equal(t, "gauge", gauge["type"])
+	gaugeDefaults := gauge["fieldConfig"].(map[string]interface{})["defaults"].(map[string]interface{})
+	require.Equal(t, float64(1), gaugeDefaults["max"])
+	require.Equal(t, float64(0), gaugeDefaults["min"])
+
+	bg := rendered[4].(map[string]interface{})
+	require.Equal(t, "bargauge", bg["type"])
+	mappings := bg["fieldConfig"].(map[string]interface{})["defaults"].(map[string]interface{})["mappings"].([]interface{})
+	require.Equal(t, map[string]interface{}{
+		"options": map[string]interface{}{"1": map[string]interface{}{"text": "up"}},
+		"type":    "value",
+	}, mappings[0])
+
+	table := rendered[5].(map[string]interface{})
+	require.Equal(t, "table", table["type"])
+	require.Equal(t, "table", table["targets"].([]interface{})[0].(map[string]interface{})["format"])
+}
+
+func TestRenderer_EscapesStrings(t *testing.T) {
+	cfg, err := config.Parse("../test/config.yml")
+	require.NoError(t, err)
+	r := newRenderer(cfg)
+	g := Graph{
+		Description: "Duration of requests.\nIn seconds.",
+		Queries:     []GraphQuery{{Query: `rate(requests_total{path=~"/api/\\d+"}[5m])`}},
+		Title:       `Requests of "api"`,
+	}
+
+	out, err := r.Render(Dashboard{Title: "Escape"}, []Panel{g})
+	require.NoError(t, err)
+	var db map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(out), &db))
+	panel := db["panels"].([]interface{})[0].(map[string]interface{})
+	require.Equal(t, "Duration of requests.\nIn seconds.", panel["description"])
+	require.Equal(t, `Requests of "api"`, panel["title"])
+	target := panel["targets"].([]interface{})[0].(map[string]interface{})
+	require.Equal(t, `rate(requests_total{path=~"/api/\\d+"}[5m])`, target["expr"])
+}
+
+func TestRenderer_InvalidJSON(t *testing.T) {
+	cfg, err := config.Parse("../test/config.yml")
+	require.NoError(t, err)
+	r := newRenderer(cfg)
+	_, err = r.Render(Dashboard{}, []Panel{Graph{HasThreshold: true, ThresholdOP: "gt", ThresholdValue: "high"}})
+	require.Error(t, err)
+
+	r.graphTpl, err = mustache.ParseString(`{"title": {{{Title}}}}`)
+	require.NoError(t, err)
+	_, err = r.Render(Dashboard{}, []Panel{Graph{Title: "Graph"}})
+	require.EqualError(t, err, "render graph panel 1: template returned invalid JSON")
+}
+
+func TestRenderer_CollapsedRows(t *testing.T) {
+	cfg, err := config.Parse("../test/config.yml")
+	require.NoError(t, err)
+	r := newRenderer(cfg)
+	panels := []Panel{
+		Row{Title: "General"},
+		Graph{Title: "Graph 1"},
+		Row{Collapsed: true, Title: "Collapsed"},
+		Graph{Title: "Graph 2"},
+		Singlestat{Title: "Singlestat"},
+		Row{Title: "Expanded"},
+		Graph{Title: "Graph 3"},
+	}
+
+	out, err := r.Render(Dashboard{Title: "Rows"}, panels)
+	require.NoError(t, err)
+	var db struct {
+		Panels []struct {
+			Collapsed bool `json:"collapsed"`
+			GridPos   struct {
+				Y int `json:"y"`
+			} `json:"gridPos"`
+			Panels []struct {
+				GridPos struct {
+					Y int `json:"y"`
+				} `json:"gridPos"`
+				Title string `json:"title"`
+			} `json:"panels"`
+			Title string `json:"title"`
+		} `json:"panels"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(out), &db))
+	require.Len(t, db.Panels, 5)
+	collapsed := db.Panels[2]
+	require.Equal(t, "Collapsed", collapsed.Title)
+	require.True(t, collapsed.Collapsed)
+	require.Equal(t, 6, collapsed.GridPos.Y)
+	require.Len(t, collapsed.Panels, 2)
+	require.Equal(t, "Graph 2", collapsed.Panels[0].Title)
+	require.Equal(t, 7, collapsed.Panels[0].GridPos.Y)
+	require.Equal(t, "Singlestat", collapsed.Panels[1].Title)
+	require.Equal(t, "Expanded", db.Panels[3].Title)
+	require.False(t, db.Panels[3].Collapsed)
+	require.Equal(t, 7, db.Panels[3].GridPos.Y)
+	require.Equal(t, "Graph 3", db.Panels[4].Title)
+	require.Equal(t, 8, db.Panels[4].GridPos.Y)
+}
+
+// TestRenderer_TemplatesMatchModel ensures that the templates in the directory "templates", which users can modify to
+// override the JSON of panels, create the same JSON as the model.
+func TestRenderer_TemplatesMatchModel(t *testing.T) {
+	cfg, err := config.Parse("../test/config.yml")
+	require.NoError(t, err)
+	panels := []Panel{
+		Row{Title: `Row "quoted"`},
+		Graph{
+			Description:     "Help text with a \\ and a\nnewline",
+			Format:          "s",
+			HasLegend:       true,
+			HasThreshold:    true,
+			Legend:          "{{handler}}",
+			Queries:         []GraphQuery{{Query: `rate(requests_total{path=~"/api/\\d+"}[5m])`}, {Hide: true, Legend: "limit", Query: "limit"}},
+			SeriesOverrides: []SeriesOverride{{Alias: "limit", YAxis: 2}},
+			ThresholdOP:     "gt",
+			ThresholdValue:  "0.5",
+			Title:           "Graph",
+		},
+		Singlestat{Format: "short", Query: "sum(up)", ThresholdInvertNo: true, ThresholdValue: "3", Title: "Singlestat", ValueName: "current"},
+		Singlestat{Datasource: "other", Query: "sum(up)", Title: "Singlestat", ValueName: "avg"},
+		Heatmap{Format: "s", Legend: "{{le}}", Query: "sum by (le) (rate(duration_seconds_bucket[5m]))", Title: "Heatmap"},
+		Timeseries{
+			FieldConfig:     FieldConfig{Format: "s", Thresholds: []ThresholdStep{{Color: "green"}, {Color: "red", Value: "1"}}},
+			HasLegend:       true,
+			Legend:          "{{handler}}",
+			Queries:         []GraphQuery{{Query: "duration_seconds"}},
+			SeriesOverrides: []TimeseriesOverride{{Alias: "/limit/", Matcher: "byRegexp", Placement: "right"}},
+			Title:           "Timeseries",
+		},
+		Stat{FieldConfig: FieldConfig{Mappings: []ValueMapping{{Text: "down", Value: "0"}, {Text: "up", Value: "1"}}}, Query: "up", Title: "Stat"},
+		Gauge{FieldConfig: FieldConfig{Max: "1", Min: "0"}, Query: "avg(up)", Reduce: "mean", Title: "Gauge"},
+		BarGauge{Legend: "{{job}}", Query: "up", Title: "BarGauge"},
+		Table{Query: "up", Title: "Table"},
+		Row{Collapsed: true, Title: "Collapsed"},
+		Graph{Queries: []GraphQuery{{Query: "up"}}, Title: "Nested"},
+		Row{Repeat: "handler", Title: "Repeated $handler"},
+		Graph{Queries: []GraphQuery{{Query: `requests_total{handler=~"$handler"}`}}, Title: "Repeated"},
+	}
+	db := Dashboard{Title: "Templates", Variables: labelsToVariables("prometheus", []string{"job", "instance"}, `up{job="node"}`)}
+	db.Variables = append(db.Variables, Variable{Datasource: "prometheus", IncludeAll: true, Multi: true, Name: "handler", Query: "label_values(requests_total, handler)"})
+	for _, modern := range []bool{false, true} {
+		withModel := newRenderer(cfg)
+		withModel.modern = modern
+		withTemplates := newRenderer(cfg)
+		withTemplates.modern = modern
+		for name, tpl := range map[string]**mustache.Template{
+			"bargauge":   &withTemplates.barGaugeTpl,
+			"dashboard":  &withTemplates.dashboardTpl,
+			"gauge":      &withTemplates.gaugeTpl,
+			"graph":      &withTemplates.graphTpl,
+			"heatmap":    &withTemplates.heatmapTpl,
+			"row":        &withTemplates.rowTpl,
+			"singlestat": &withTemplates.singlestatTpl,
+			"stat":       &withTemplates.statTpl,
+			"table":      &withTemplates.tableTpl,
+			"timeseries": &withTemplates.timeseriesTpl,
+		} {
+			*tpl, err = mustache.ParseFile("../templates/" + name + ".json.mustache")
+			require.NoError(t, err)
+		}
+
+		expected, err := withModel.Render(db, panels)
+		require.NoError(t, err)
+		actual, err := withTemplates.Render(db, panels)
+		require.NoError(t, err)
+		require.JSONEq(t, expected, actual, "modern: %v", modern)
+	}
+}
+
+func TestLabelsToVariables(t *testing.T) {
+	variables := labelsToVariables("prometheus", []string{"job", "instance"}, "process_start_time_seconds")
+	require.Equal(t, []Variable{
+		{Datasource: "prometheus", IncludeAll: true, Multi: true, Name: "job", Query: "label_values(process_start_time_seconds, job)"},
+		{Datasource: "prometheus", IncludeAll: true, Multi: true, Name: "instance", Query: `label_values(process_start_time_seconds{job=~"$job"}, instance)`},
+	}, variables)
+
+	variables = labelsToVariables("prometheus", []string{"job", "instance"}, `up{job="node"}`)
+	require.Equal(t, `label_values(up{job="node"}, job)`, variables[0].Query)
+	require.Equal(t, `label_values(up{job="node",job=~"$job"}, instance)`, variables[1].Query)
+}
+
+func TestAddLabelMatchers(t *testing.T) {
+	require.Equal(t, "up", addLabelMatchers("up", nil))
+	require.Equal(t, `up{instance=~"$instance"}`, addLabelMatchers("up", []string{"instance"}))
+	require.Equal(t, `up{instance=~"$instance"}`, addLabelMatchers("up{}", []string{"instance"}))
+	require.Equal(t, `up{job="node",instance=~"$instance",mode='idle'}`, addLabelMatchers(`up{job="node"}`, []string{"instance"}, "mode='idle'"))
+	require.Equal(t, `{job=~"$job",instance=~"$instance"}`, labelSelectors([]string{"job", "instance"}))
+	require.Equal(t, "", labelSelectors(nil))
+}
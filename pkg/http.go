@@ -0,0 +1,161 @@
+package v1
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/wndhydrnt/autoboard/pkg/config"
+)
+
+const (
+	headerTenant = "X-Scope-OrgID"
+)
+
+// headerRoundTripper sets static headers on every request before passing it on.
+type headerRoundTripper struct {
+	headers map[string]string
+	next    http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (h *headerRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the original request.
+	r = r.Clone(r.Context())
+	for k, v := range h.headers {
+		r.Header.Set(k, v)
+	}
+
+	return h.next.RoundTrip(r)
+}
+
+// newRoundTripper returns a http.RoundTripper that authenticates each request and sets custom headers as configured.
+// The X-Scope-OrgID header is set if tenant is not empty.
+func newRoundTripper(cfg config.HTTPClientConfig, tenant string) (http.RoundTripper, error) {
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("create TLS config: %w", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	if cfg.ProxyURL != "" {
+		proxyURL, err := url.Parse(cfg.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("parse proxy URL: %w", err)
+		}
+
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	headers := map[string]string{}
+	for k, v := range cfg.Headers {
+		headers[k] = v
+	}
+
+	if tenant != "" {
+		headers[headerTenant] = tenant
+	}
+
+	authorization, err := authorizationHeader(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if authorization != "" {
+		headers["Authorization"] = authorization
+	}
+
+	if len(headers) == 0 {
+		return transport, nil
+	}
+
+	return &headerRoundTripper{headers: headers, next: transport}, nil
+}
+
+func authorizationHeader(cfg config.HTTPClientConfig) (string, error) {
+	hasBasicAuth := cfg.BasicAuthUsername != "" || cfg.BasicAuthPassword != "" || cfg.BasicAuthPasswordFile != ""
+	hasBearerToken := cfg.BearerToken != "" || cfg.BearerTokenFile != ""
+	if hasBasicAuth && hasBearerToken {
+		return "", fmt.Errorf("at most one of basic_auth and bearer_token can be configured")
+	}
+
+	if cfg.BearerToken != "" && cfg.BearerTokenFile != "" {
+		return "", fmt.Errorf("at most one of bearer_token and bearer_token_file can be configured")
+	}
+
+	if cfg.BasicAuthPassword != "" && cfg.BasicAuthPasswordFile != "" {
+		return "", fmt.Errorf("at most one of basic_auth password and password_file can be configured")
+	}
+
+	if hasBasicAuth {
+		password := cfg.BasicAuthPassword
+		if cfg.BasicAuthPasswordFile != "" {
+			b, err := ioutil.ReadFile(cfg.BasicAuthPasswordFile)
+			if err != nil {
+				return "", fmt.Errorf("read basic auth password file %s: %w", cfg.BasicAuthPasswordFile, err)
+			}
+
+			password = strings.TrimSpace(string(b))
+		}
+
+		credentials := base64.StdEncoding.EncodeToString([]byte(cfg.BasicAuthUsername + ":" + password))
+		return "Basic " + credentials, nil
+	}
+
+	token := cfg.BearerToken
+	if cfg.BearerTokenFile != "" {
+		b, err := ioutil.ReadFile(cfg.BearerTokenFile)
+		if err != nil {
+			return "", fmt.Errorf("read bearer token file %s: %w", cfg.BearerTokenFile, err)
+		}
+
+		token = strings.TrimSpace(string(b))
+	}
+
+	if token == "" {
+		return "", nil
+	}
+
+	return "Bearer " + token, nil
+}
+
+func newTLSConfig(cfg config.HTTPClientConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: cfg.TLSInsecureSkipVerify,
+		ServerName:         cfg.TLSServerName,
+	}
+	if cfg.TLSCAFile != "" {
+		b, err := ioutil.ReadFile(cfg.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA file %s: %w", cfg.TLSCAFile, err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no certificate found in CA file %s", cfg.TLSCAFile)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		return nil, fmt.Errorf("cert_file and key_file must be configured together")
+	}
+
+	if cfg.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
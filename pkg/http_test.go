@@ -0,0 +1,50 @@
+package v1
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/wndhydrnt/autoboard/pkg/config"
+)
+
+func TestNewRoundTripper(t *testing.T) {
+	dir, err := ioutil.TempDir("", "autoboard")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	err = ioutil.WriteFile(tokenFile, []byte("secret\n"), 0600)
+	require.NoError(t, err)
+
+	var received http.Header
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header
+	}))
+	defer s.Close()
+
+	rt, err := newRoundTripper(config.HTTPClientConfig{
+		BearerTokenFile: tokenFile,
+		Headers:         map[string]string{"x-custom": "value"},
+	}, "team-a")
+	require.NoError(t, err)
+	c := &http.Client{Transport: rt}
+	resp, err := c.Get(s.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	require.Equal(t, "Bearer secret", received.Get("Authorization"))
+	require.Equal(t, "value", received.Get("X-Custom"))
+	require.Equal(t, "team-a", received.Get(headerTenant))
+}
+
+func TestNewRoundTripper_InvalidConfig(t *testing.T) {
+	_, err := newRoundTripper(config.HTTPClientConfig{BasicAuthUsername: "admin", BearerToken: "secret"}, "")
+	require.EqualError(t, err, "at most one of basic_auth and bearer_token can be configured")
+
+	_, err = newRoundTripper(config.HTTPClientConfig{TLSCertFile: "client.crt"}, "")
+	require.EqualError(t, err, "create TLS config: cert_file and key_file must be configured together")
+}
@@ -0,0 +1,162 @@
+package v1
+
+import (
+	"github.com/wndhydrnt/autoboard/pkg/config"
+)
+
+// gridWidth is the width of a dashboard in Grafana.
+const gridWidth = 24
+
+// gridPos is the position and the size of a panel on a dashboard.
+type gridPos struct {
+	height int
+	width  int
+	x      int
+	y      int
+}
+
+// layoutPanels returns the position of every panel according to the layout of the Renderer.
+// The position of a panel that the Renderer cannot render is the zero value.
+func (r *Renderer) layoutPanels(panels []Panel) []gridPos {
+	positions := make([]gridPos, len(panels))
+	line := []int{}
+	lineWidth := 0
+	posY := 0
+	// collapsedY is the position of the last row if it is collapsed or -1 otherwise.
+	// The panels of a collapsed row take no space on the dashboard. The next row follows directly after it.
+	collapsedY := -1
+	// flush places the panels of the current line and moves to the next line.
+	flush := func() {
+		if len(line) == 0 {
+			return
+		}
+
+		if r.layout == config.LayoutCompact || r.layout == config.LayoutFill {
+			fillLine(positions, line, lineWidth)
+		}
+
+		posX := 0
+		for _, i := range line {
+			positions[i].x = posX
+			positions[i].y = posY
+			posX = posX + positions[i].width
+		}
+
+		posY = posY + r.panelHeight
+		line = []int{}
+		lineWidth = 0
+	}
+
+	for i, p := range panels {
+		if p.Type() == PanelTypeRow {
+			flush()
+			if collapsedY != -1 {
+				posY = collapsedY + 1
+				collapsedY = -1
+			}
+
+			if p.(Row).Collapsed {
+				collapsedY = posY
+			}
+
+			positions[i] = gridPos{height: 1, width: gridWidth, y: posY}
+			// A row always has a height of 1
+			posY = posY + 1
+			continue
+		}
+
+		width := r.panelWidth(p)
+		if width == 0 {
+			continue
+		}
+
+		if lineWidth+width > gridWidth {
+			flush()
+		}
+
+		// The strip of compact singlestats does not share a line with other panels.
+		if r.layout == config.LayoutCompact && len(line) > 0 && isSinglestatLike(panels[line[0]]) != isSinglestatLike(p) {
+			flush()
+		}
+
+		positions[i] = gridPos{height: r.panelHeight, width: width}
+		line = append(line, i)
+		lineWidth = lineWidth + width
+	}
+
+	flush()
+	return positions
+}
+
+// panelWidth returns the width of a panel before it is stretched to fill a line.
+// It returns 0 if the Renderer cannot render the panel.
+func (r *Renderer) panelWidth(p Panel) int {
+	var width int
+	switch p.Type() {
+	case PanelTypeGauge, PanelTypeSinglestat, PanelTypeStat:
+		width = r.panelWidthSinglestat
+	case PanelTypeBarGauge, PanelTypeGraph, PanelTypeHeatmap, PanelTypeTable, PanelTypeTimeseries:
+		width = r.panelWidthGraph
+	default:
+		return 0
+	}
+
+	if r.layout == config.LayoutGrid {
+		return gridWidth / r.gridColumns
+	}
+
+	return width
+}
+
+// fillLine stretches the panels of a line so that they fill the width of the dashboard.
+// Every panel keeps its share of the width of the line. Columns left over by rounding go to the leftmost panels.
+func fillLine(positions []gridPos, line []int, lineWidth int) {
+	filled := 0
+	for _, i := range line {
+		positions[i].width = positions[i].width * gridWidth / lineWidth
+		filled = filled + positions[i].width
+	}
+
+	for n := 0; filled < gridWidth; n++ {
+		positions[line[n%len(line)]].width++
+		filled++
+	}
+}
+
+// compactSinglestats moves singlestat, stat and gauge panels in front of all other panels of their row.
+// The order of the panels of each kind stays the same.
+func compactSinglestats(panels []Panel) []Panel {
+	result := make([]Panel, 0, len(panels))
+	var singlestats, others []Panel
+	flush := func() {
+		result = append(result, singlestats...)
+		result = append(result, others...)
+		singlestats = nil
+		others = nil
+	}
+
+	for _, p := range panels {
+		switch {
+		case p.Type() == PanelTypeRow:
+			flush()
+			result = append(result, p)
+		case isSinglestatLike(p):
+			singlestats = append(singlestats, p)
+		default:
+			others = append(others, p)
+		}
+	}
+
+	flush()
+	return result
+}
+
+// isSinglestatLike reports whether a panel displays a single value.
+func isSinglestatLike(p Panel) bool {
+	switch p.Type() {
+	case PanelTypeGauge, PanelTypeSinglestat, PanelTypeStat:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,123 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/wndhydrnt/autoboard/pkg/config"
+)
+
+func TestRenderer_layoutPanels(t *testing.T) {
+	panels := []Panel{
+		Row{Title: "Row"},
+		Graph{Title: "Graph 1"},
+		Singlestat{Title: "Singlestat 1"},
+		Singlestat{Title: "Singlestat 2"},
+		Graph{Title: "Graph 2"},
+		Row{Title: "Row 2"},
+		Stat{Title: "Stat"},
+	}
+	testCases := []struct {
+		name      string
+		layout    string
+		expected  []gridPos
+		reordered []string
+	}{
+		{
+			name:   "flow",
+			layout: config.LayoutFlow,
+			expected: []gridPos{
+				{height: 1, width: 24, x: 0, y: 0},
+				{height: 5, width: 12, x: 0, y: 1},
+				{height: 5, width: 6, x: 12, y: 1},
+				{height: 5, width: 6, x: 18, y: 1},
+				{height: 5, width: 12, x: 0, y: 6},
+				{height: 1, width: 24, x: 0, y: 11},
+				{height: 5, width: 6, x: 0, y: 12},
+			},
+		},
+		{
+			name:   "fill",
+			layout: config.LayoutFill,
+			expected: []gridPos{
+				{height: 1, width: 24, x: 0, y: 0},
+				{height: 5, width: 12, x: 0, y: 1},
+				{height: 5, width: 6, x: 12, y: 1},
+				{height: 5, width: 6, x: 18, y: 1},
+				{height: 5, width: 24, x: 0, y: 6},
+				{height: 1, width: 24, x: 0, y: 11},
+				{height: 5, width: 24, x: 0, y: 12},
+			},
+		},
+		{
+			name:   "grid",
+			layout: config.LayoutGrid,
+			expected: []gridPos{
+				{height: 1, width: 24, x: 0, y: 0},
+				{height: 5, width: 8, x: 0, y: 1},
+				{height: 5, width: 8, x: 8, y: 1},
+				{height: 5, width: 8, x: 16, y: 1},
+				{height: 5, width: 8, x: 0, y: 6},
+				{height: 1, width: 24, x: 0, y: 11},
+				{height: 5, width: 8, x: 0, y: 12},
+			},
+		},
+		{
+			name:   "compact",
+			layout: config.LayoutCompact,
+			expected: []gridPos{
+				{height: 1, width: 24, x: 0, y: 0},
+				{height: 5, width: 12, x: 0, y: 1},
+				{height: 5, width: 12, x: 12, y: 1},
+				{height: 5, width: 12, x: 0, y: 6},
+				{height: 5, width: 12, x: 12, y: 6},
+				{height: 1, width: 24, x: 0, y: 11},
+				{height: 5, width: 24, x: 0, y: 12},
+			},
+			reordered: []string{"Row", "Singlestat 1", "Singlestat 2", "Graph 1", "Graph 2", "Row 2", "Stat"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &Renderer{gridColumns: 3, layout: tc.layout, panelHeight: 5, panelWidthGraph: 12, panelWidthSinglestat: 6}
+			ordered := panels
+			if tc.layout == config.LayoutCompact {
+				ordered = compactSinglestats(panels)
+				titles := []string{}
+				for _, p := range ordered {
+					titles = append(titles, panelTitle(p))
+				}
+
+				require.Equal(t, tc.reordered, titles)
+			}
+
+			require.Equal(t, tc.expected, r.layoutPanels(ordered))
+		})
+	}
+}
+
+func TestFillLine(t *testing.T) {
+	positions := []gridPos{{width: 10}, {width: 4}, {width: 6}}
+	fillLine(positions, []int{0, 1}, 14)
+	require.Equal(t, []gridPos{{width: 18}, {width: 6}, {width: 6}}, positions)
+
+	positions = []gridPos{{width: 5}, {width: 5}, {width: 5}, {width: 5}, {width: 5}}
+	fillLine(positions, []int{0, 1, 2, 3, 4}, 25)
+	require.Equal(t, []gridPos{{width: 5}, {width: 5}, {width: 5}, {width: 5}, {width: 4}}, positions)
+}
+
+func panelTitle(p Panel) string {
+	switch v := p.(type) {
+	case Graph:
+		return v.Title
+	case Row:
+		return v.Title
+	case Singlestat:
+		return v.Title
+	case Stat:
+		return v.Title
+	default:
+		return ""
+	}
+}
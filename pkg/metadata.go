@@ -0,0 +1,181 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	pav1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/textparse"
+	log "github.com/sirupsen/logrus"
+	"github.com/wndhydrnt/autoboard/pkg/config"
+)
+
+const (
+	// metadataSeriesLookback is the time range in which MetadataSource looks for series.
+	metadataSeriesLookback = 10 * time.Minute
+)
+
+// Suffixes of series that belong to a metric family with a different name, e.g. the "_bucket" series of a histogram.
+var familySuffixes = []string{"_bucket", "_count", "_sum", "_total", "_created", "_gcount", "_gsum", "_info"}
+
+// MetadataSource reads Metrics from Prometheus instead of the endpoint of a service.
+// Types and help texts come from the metadata API, label keys from the series API.
+type MetadataSource struct {
+	API pav1.API
+	// Selector selects the series of a service, e.g. {job="node"}.
+	Selector string
+}
+
+// NewMetadataSource returns a MetadataSource that connects to Prometheus as configured by the prometheus settings.
+func NewMetadataSource(cfg config.Config, selector string) (*MetadataSource, error) {
+	api, err := newPrometheusAPIFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MetadataSource{API: api, Selector: selector}, nil
+}
+
+// Metrics implements MetricSource.
+// Series for which Prometheus does not know any metadata are skipped, as are the labels of the target, e.g. "job" and
+// "instance", because they are not exposed by the service itself.
+func (m *MetadataSource) Metrics() ([]Metric, error) {
+	ctx := context.Background()
+	now := time.Now()
+	series, _, err := m.API.Series(ctx, []string{m.Selector}, now.Add(-metadataSeriesLookback), now)
+	if err != nil {
+		return nil, fmt.Errorf("read series from Prometheus: %w", err)
+	}
+
+	metadata, targetLabels, err := m.readMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := map[string]*Metric{}
+	labelKeys := map[string]map[string]struct{}{}
+	counters := map[string]*cardinalityCounter{}
+	for _, ls := range series {
+		name := string(ls[model.MetricNameLabel])
+		family, ok := findFamily(name, metadata)
+		if !ok {
+			log.Debugf("no metadata found for metric %s", name)
+			continue
+		}
+
+		if _, exists := metrics[family]; !exists {
+			md := metadata[family]
+			metrics[family] = &Metric{Help: md.Help, Name: family, Type: textparse.MetricType(md.Type), Unit: md.Unit}
+			labelKeys[family] = map[string]struct{}{}
+			counters[family] = newCardinalityCounter()
+		}
+
+		if normalized := normalizeName(family, metrics[family].Type, name); normalized != family {
+			metrics[family].Name = normalized
+		}
+
+		for ln := range ls {
+			if ln == model.MetricNameLabel {
+				continue
+			}
+
+			if _, isTargetLabel := targetLabels[string(ln)]; isTargetLabel {
+				continue
+			}
+
+			labelKeys[family][string(ln)] = struct{}{}
+		}
+
+		lset := labels.Labels{}
+		for ln, lv := range ls {
+			lset = append(lset, labels.Label{Name: string(ln), Value: string(lv)})
+		}
+
+		counters[family].add(lset, targetLabels)
+	}
+
+	names := []string{}
+	for n := range metrics {
+		names = append(names, n)
+	}
+
+	sort.Strings(names)
+	result := []Metric{}
+	for _, n := range names {
+		metric := metrics[n]
+		for lk := range labelKeys[n] {
+			metric.LabelKeys = append(metric.LabelKeys, lk)
+		}
+
+		sort.Strings(metric.LabelKeys)
+		counters[n].apply(metric)
+		result = append(result, *metric)
+	}
+
+	return dropCreatedMetrics(result), nil
+}
+
+// String implements fmt.Stringer.
+func (m *MetadataSource) String() string {
+	return m.Selector
+}
+
+// readMetadata reads the metadata of all metrics of the targets that match the selector.
+// It falls back to the metadata of all metrics known to Prometheus if the metadata of targets cannot be read, e.g.
+// because the selector contains labels that targets do not have.
+func (m *MetadataSource) readMetadata(ctx context.Context) (map[string]pav1.Metadata, map[string]struct{}, error) {
+	metadata := map[string]pav1.Metadata{}
+	targetLabels := map[string]struct{}{}
+	tmd, err := m.API.TargetsMetadata(ctx, m.Selector, "", "")
+	if err == nil && len(tmd) > 0 {
+		for _, md := range tmd {
+			metadata[md.Metric] = pav1.Metadata{Help: md.Help, Type: md.Type, Unit: md.Unit}
+			for ln := range md.Target {
+				targetLabels[ln] = struct{}{}
+			}
+		}
+
+		return metadata, targetLabels, nil
+	}
+
+	log.Debugf("reading metadata of targets failed, falling back to metadata of all metrics: %v", err)
+	all, err := m.API.Metadata(ctx, "", "")
+	if err != nil {
+		return nil, nil, fmt.Errorf("read metadata from Prometheus: %w", err)
+	}
+
+	for name, mds := range all {
+		if len(mds) > 0 {
+			metadata[name] = mds[0]
+		}
+	}
+
+	targetLabels[string(model.JobLabel)] = struct{}{}
+	targetLabels[string(model.InstanceLabel)] = struct{}{}
+	return metadata, targetLabels, nil
+}
+
+// findFamily returns the name of the metric family a series belongs to.
+func findFamily(name string, metadata map[string]pav1.Metadata) (string, bool) {
+	if _, ok := metadata[name]; ok {
+		return name, true
+	}
+
+	for _, suffix := range familySuffixes {
+		if !strings.HasSuffix(name, suffix) {
+			continue
+		}
+
+		family := strings.TrimSuffix(name, suffix)
+		if _, ok := metadata[family]; ok {
+			return family, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,55 @@
+package v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/prometheus/pkg/textparse"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	metadataSeriesResponse = `{"status":"success","data":[
+{"__name__":"go_goroutines","instance":"localhost:9090","job":"prometheus"},
+{"__name__":"prometheus_http_requests_total","code":"200","handler":"/metrics","instance":"localhost:9090","job":"prometheus"},
+{"__name__":"prometheus_http_request_duration_seconds_bucket","handler":"/metrics","instance":"localhost:9090","job":"prometheus","le":"0.1"},
+{"__name__":"prometheus_http_request_duration_seconds_count","handler":"/metrics","instance":"localhost:9090","job":"prometheus"},
+{"__name__":"up","instance":"localhost:9090","job":"prometheus"}
+]}`
+	metadataTargetsResponse = `{"status":"success","data":[
+{"target":{"instance":"localhost:9090","job":"prometheus"},"metric":"go_goroutines","type":"gauge","help":"Number of goroutines that currently exist.","unit":""},
+{"target":{"instance":"localhost:9090","job":"prometheus"},"metric":"prometheus_http_requests_total","type":"counter","help":"Counter of HTTP requests.","unit":""},
+{"target":{"instance":"localhost:9090","job":"prometheus"},"metric":"prometheus_http_request_duration_seconds","type":"histogram","help":"Histogram of latencies for HTTP requests.","unit":""}
+]}`
+)
+
+func TestMetadataSource_Metrics(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/series":
+			r.ParseForm()
+			require.Equal(t, []string{`{job="prometheus"}`}, r.Form["match[]"])
+			w.Write([]byte(metadataSeriesResponse))
+		case "/api/v1/targets/metadata":
+			require.Equal(t, `{job="prometheus"}`, r.URL.Query().Get("match_target"))
+			w.Write([]byte(metadataTargetsResponse))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	api, err := NewPrometheusAPI(s.URL, http.DefaultTransport)
+	require.NoError(t, err)
+	source := &MetadataSource{API: api, Selector: `{job="prometheus"}`}
+	metrics, err := source.Metrics()
+	require.NoError(t, err)
+
+	expected := []Metric{
+		{Help: "Number of goroutines that currently exist.", Name: "go_goroutines", Series: 1, Type: textparse.MetricTypeGauge},
+		{Help: "Histogram of latencies for HTTP requests.", LabelKeys: []string{"handler", "le"}, LabelValues: map[string]int{"handler": 1}, Name: "prometheus_http_request_duration_seconds", Series: 1, Type: textparse.MetricTypeHistogram},
+		{Help: "Counter of HTTP requests.", LabelKeys: []string{"code", "handler"}, LabelValues: map[string]int{"code": 1, "handler": 1}, Name: "prometheus_http_requests_total", Series: 1, Type: textparse.MetricTypeCounter},
+	}
+	require.Equal(t, expected, metrics)
+}
@@ -0,0 +1,822 @@
+package v1
+
+import (
+	"encoding/json"
+)
+
+// The types in this file mirror the JSON model of a dashboard in Grafana.
+// The Renderer marshals them unless a template overrides the JSON of a panel or the dashboard.
+
+type dashboardModel struct {
+	Annotations  annotationsModel  `json:"annotations"`
+	Editable     bool              `json:"editable"`
+	GnetID       *int              `json:"gnetId"`
+	GraphTooltip int               `json:"graphTooltip"`
+	Links        []interface{}     `json:"links"`
+	Panels       []json.RawMessage `json:"panels"`
+	Refresh      string            `json:"refresh"`
+	Style        string            `json:"style"`
+	Tags         []string          `json:"tags"`
+	Templating   templatingModel   `json:"templating"`
+	Time         timeModel         `json:"time"`
+	Timepicker   timepickerModel   `json:"timepicker"`
+	Timezone     string            `json:"timezone"`
+	Title        string            `json:"title"`
+}
+
+type annotationsModel struct {
+	List []annotationModel `json:"list"`
+}
+
+type annotationModel struct {
+	BuiltIn    int    `json:"builtIn"`
+	Datasource string `json:"datasource"`
+	Enable     bool   `json:"enable"`
+	Hide       bool   `json:"hide"`
+	IconColor  string `json:"iconColor"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+}
+
+type templatingModel struct {
+	List []variableModel `json:"list"`
+}
+
+type variableModel struct {
+	AllValue       *string              `json:"allValue"`
+	Current        variableCurrentModel `json:"current"`
+	Datasource     string               `json:"datasource"`
+	Definition     string               `json:"definition"`
+	Hide           int                  `json:"hide"`
+	IncludeAll     bool                 `json:"includeAll"`
+	Label          *string              `json:"label"`
+	Multi          bool                 `json:"multi"`
+	Name           string               `json:"name"`
+	Options        []interface{}        `json:"options"`
+	Query          string               `json:"query"`
+	Refresh        int                  `json:"refresh"`
+	Regex          string               `json:"regex"`
+	SkipURLSync    bool                 `json:"skipUrlSync"`
+	Sort           int                  `json:"sort"`
+	TagValuesQuery string               `json:"tagValuesQuery"`
+	Tags           []string             `json:"tags"`
+	TagsQuery      string               `json:"tagsQuery"`
+	Type           string               `json:"type"`
+	UseTags        bool                 `json:"useTags"`
+}
+
+type variableCurrentModel struct {
+	Tags  []string `json:"tags"`
+	Text  string   `json:"text"`
+	Value []string `json:"value"`
+}
+
+type timeModel struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type timepickerModel struct {
+	RefreshIntervals []string `json:"refresh_intervals"`
+	TimeOptions      []string `json:"time_options"`
+}
+
+func newDashboardModel(db Dashboard, panels []json.RawMessage) dashboardModel {
+	m := dashboardModel{
+		Annotations: annotationsModel{List: []annotationModel{{
+			BuiltIn:    1,
+			Datasource: "-- Grafana --",
+			Enable:     true,
+			Hide:       true,
+			IconColor:  "rgba(0, 211, 255, 1)",
+			Name:       "Annotations & Alerts",
+			Type:       "dashboard",
+		}}},
+		Editable:   true,
+		Links:      []interface{}{},
+		Panels:     panels,
+		Refresh:    "1m",
+		Style:      "dark",
+		Tags:       []string{},
+		Templating: templatingModel{List: []variableModel{}},
+		Time:       timeModel{From: "now-1h", To: "now"},
+		Timepicker: timepickerModel{
+			RefreshIntervals: []string{"5s", "10s", "30s", "1m", "5m", "15m", "30m", "1h", "2h", "1d"},
+			TimeOptions:      []string{"5m", "15m", "1h", "6h", "12h", "24h", "2d", "7d", "30d"},
+		},
+		Title: db.Title,
+	}
+	for _, v := range db.Variables {
+		m.Templating.List = append(m.Templating.List, variableModel{
+			Current:    variableCurrentModel{Tags: []string{}, Value: []string{}},
+			Datasource: v.Datasource,
+			Definition: v.Query,
+			IncludeAll: v.IncludeAll,
+			Multi:      v.Multi,
+			Name:       v.Name,
+			Options:    []interface{}{},
+			Query:      v.Query,
+			Refresh:    1,
+			Sort:       1,
+			Tags:       []string{},
+			Type:       "query",
+		})
+	}
+
+	return m
+}
+
+// panelModel holds the fields that all panels have in common.
+type panelModel struct {
+	Datasource  *string       `json:"datasource"`
+	Description string        `json:"description"`
+	GridPos     gridPosModel  `json:"gridPos"`
+	Links       []string      `json:"links"`
+	Targets     []targetModel `json:"targets"`
+	Title       string        `json:"title"`
+	Type        string        `json:"type"`
+}
+
+func newPanelModel(panelType, datasource, description, title string, height, width, x, y int, targets []targetModel) panelModel {
+	m := panelModel{
+		Description: description,
+		GridPos:     gridPosModel{H: height, W: width, X: x, Y: y},
+		Links:       []string{},
+		Targets:     targets,
+		Title:       title,
+		Type:        panelType,
+	}
+	if datasource != "" {
+		m.Datasource = &datasource
+	}
+
+	return m
+}
+
+type gridPosModel struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type targetModel struct {
+	Expr           string `json:"expr"`
+	Format         string `json:"format"`
+	Hide           bool   `json:"hide,omitempty"`
+	Instant        bool   `json:"instant,omitempty"`
+	IntervalFactor int    `json:"intervalFactor"`
+	LegendFormat   string `json:"legendFormat"`
+	RefID          string `json:"refId"`
+}
+
+func newTargetModels(queries []GraphQuery) []targetModel {
+	targets := []targetModel{}
+	for _, q := range queries {
+		targets = append(targets, targetModel{
+			Expr:           q.Query,
+			Format:         "time_series",
+			Hide:           q.Hide,
+			IntervalFactor: 1,
+			LegendFormat:   q.Legend,
+			RefID:          q.RefID,
+		})
+	}
+
+	return targets
+}
+
+func newInstantTargetModel(query, legend, format string) []targetModel {
+	return []targetModel{{
+		Expr:           query,
+		Format:         format,
+		Instant:        true,
+		IntervalFactor: 1,
+		LegendFormat:   legend,
+		RefID:          "A",
+	}}
+}
+
+type rowModel struct {
+	Collapsed  bool              `json:"collapsed"`
+	Datasource *string           `json:"datasource"`
+	GridPos    gridPosModel      `json:"gridPos"`
+	Panels     []json.RawMessage `json:"panels"`
+	Repeat     string            `json:"repeat,omitempty"`
+	Title      string            `json:"title"`
+	Type       string            `json:"type"`
+}
+
+func newRowModel(r Row, panels []json.RawMessage) rowModel {
+	if panels == nil {
+		panels = []json.RawMessage{}
+	}
+
+	return rowModel{
+		Collapsed: r.Collapsed,
+		GridPos:   gridPosModel{H: 1, W: 24, X: r.PosX, Y: r.PosY},
+		Panels:    panels,
+		Repeat:    r.Repeat,
+		Title:     r.Title,
+		Type:      PanelTypeRow,
+	}
+}
+
+type graphModel struct {
+	panelModel
+	AliasColors     map[string]string     `json:"aliasColors"`
+	Bars            bool                  `json:"bars"`
+	DashLength      int                   `json:"dashLength"`
+	Dashes          bool                  `json:"dashes"`
+	Fill            int                   `json:"fill"`
+	FillGradient    int                   `json:"fillGradient"`
+	HiddenSeries    bool                  `json:"hiddenSeries"`
+	Legend          graphLegendModel      `json:"legend"`
+	Lines           bool                  `json:"lines"`
+	Linewidth       int                   `json:"linewidth"`
+	NullPointMode   string                `json:"nullPointMode"`
+	Options         graphOptionsModel     `json:"options"`
+	Percentage      bool                  `json:"percentage"`
+	Pointradius     int                   `json:"pointradius"`
+	Points          bool                  `json:"points"`
+	Renderer        string                `json:"renderer"`
+	SeriesOverrides []seriesOverrideModel `json:"seriesOverrides"`
+	SpaceLength     int                   `json:"spaceLength"`
+	Stack           bool                  `json:"stack"`
+	SteppedLine     bool                  `json:"steppedLine"`
+	Thresholds      []graphThresholdModel `json:"thresholds"`
+	TimeFrom        *string               `json:"timeFrom"`
+	TimeRegions     []interface{}         `json:"timeRegions"`
+	TimeShift       *string               `json:"timeShift"`
+	Tooltip         graphTooltipModel     `json:"tooltip"`
+	Xaxis           graphXAxisModel       `json:"xaxis"`
+	Yaxes           []graphYAxisModel     `json:"yaxes"`
+	Yaxis           graphYAxisAlignModel  `json:"yaxis"`
+}
+
+type graphLegendModel struct {
+	AlignAsTable bool `json:"alignAsTable"`
+	Avg          bool `json:"avg"`
+	Current      bool `json:"current"`
+	HideEmpty    bool `json:"hideEmpty"`
+	HideZero     bool `json:"hideZero"`
+	Max          bool `json:"max"`
+	Min          bool `json:"min"`
+	Show         bool `json:"show"`
+	Total        bool `json:"total"`
+	Values       bool `json:"values"`
+}
+
+type graphOptionsModel struct {
+	DataLinks []interface{} `json:"dataLinks"`
+}
+
+type seriesOverrideModel struct {
+	Alias string `json:"alias"`
+	Yaxis int    `json:"yaxis"`
+}
+
+type graphThresholdModel struct {
+	ColorMode string      `json:"colorMode"`
+	Fill      bool        `json:"fill"`
+	Line      bool        `json:"line"`
+	Op        string      `json:"op"`
+	Value     json.Number `json:"value"`
+	Yaxis     string      `json:"yaxis"`
+}
+
+type graphTooltipModel struct {
+	Shared    bool   `json:"shared"`
+	Sort      int    `json:"sort"`
+	ValueType string `json:"value_type"`
+}
+
+type graphXAxisModel struct {
+	Buckets *int          `json:"buckets"`
+	Mode    string        `json:"mode"`
+	Name    *string       `json:"name"`
+	Show    bool          `json:"show"`
+	Values  []interface{} `json:"values"`
+}
+
+type graphYAxisModel struct {
+	Format  string   `json:"format"`
+	Label   *string  `json:"label"`
+	LogBase int      `json:"logBase"`
+	Max     *float64 `json:"max"`
+	Min     *float64 `json:"min"`
+	Show    bool     `json:"show"`
+}
+
+type graphYAxisAlignModel struct {
+	Align      bool     `json:"align"`
+	AlignLevel *float64 `json:"alignLevel"`
+}
+
+func newGraphModel(g Graph) graphModel {
+	m := graphModel{
+		panelModel:  newPanelModel(PanelTypeGraph, g.Datasource, g.Description, g.Title, g.Height, g.Width, g.PosX, g.PosY, newTargetModels(g.Queries)),
+		AliasColors: map[string]string{},
+		DashLength:  10,
+		Fill:        1,
+		Legend: graphLegendModel{
+			AlignAsTable: g.HasLegend,
+			HideEmpty:    true,
+			HideZero:     true,
+			Show:         g.HasLegend,
+			Total:        g.HasLegend,
+			Values:       g.HasLegend,
+		},
+		Lines:           true,
+		Linewidth:       1,
+		NullPointMode:   "null",
+		Options:         graphOptionsModel{DataLinks: []interface{}{}},
+		Pointradius:     2,
+		Renderer:        "flot",
+		SeriesOverrides: []seriesOverrideModel{},
+		SpaceLength:     10,
+		Thresholds:      []graphThresholdModel{},
+		TimeRegions:     []interface{}{},
+		Tooltip:         graphTooltipModel{Shared: true, Sort: 2, ValueType: "individual"},
+		Xaxis:           graphXAxisModel{Mode: "time", Show: true, Values: []interface{}{}},
+		Yaxes: []graphYAxisModel{
+			{Format: g.Format, LogBase: 1, Show: true},
+			{Format: "short", LogBase: 1, Show: true},
+		},
+	}
+	for _, so := range g.SeriesOverrides {
+		m.SeriesOverrides = append(m.SeriesOverrides, seriesOverrideModel{Alias: so.Alias, Yaxis: so.YAxis})
+	}
+
+	if g.HasThreshold {
+		m.Thresholds = append(m.Thresholds, graphThresholdModel{
+			ColorMode: "critical",
+			Fill:      true,
+			Line:      true,
+			Op:        g.ThresholdOP,
+			Value:     json.Number(g.ThresholdValue),
+			Yaxis:     "left",
+		})
+	}
+
+	return m
+}
+
+type heatmapModel struct {
+	panelModel
+	Cards           heatmapCardsModel   `json:"cards"`
+	Color           heatmapColorModel   `json:"color"`
+	DataFormat      string              `json:"dataFormat"`
+	Heatmap         struct{}            `json:"heatmap"`
+	HideZeroBuckets bool                `json:"hideZeroBuckets"`
+	HighlightCards  bool                `json:"highlightCards"`
+	Legend          showModel           `json:"legend"`
+	ReverseYBuckets bool                `json:"reverseYBuckets"`
+	TimeFrom        *string             `json:"timeFrom"`
+	TimeShift       *string             `json:"timeShift"`
+	Tooltip         heatmapTooltipModel `json:"tooltip"`
+	XAxis           showModel           `json:"xAxis"`
+	XBucketNumber   *int                `json:"xBucketNumber"`
+	XBucketSize     *string             `json:"xBucketSize"`
+	YAxis           heatmapYAxisModel   `json:"yAxis"`
+	YBucketBound    string              `json:"yBucketBound"`
+	YBucketNumber   *int                `json:"yBucketNumber"`
+	YBucketSize     *float64            `json:"yBucketSize"`
+}
+
+type heatmapCardsModel struct {
+	CardPadding *int `json:"cardPadding"`
+	CardRound   *int `json:"cardRound"`
+}
+
+type heatmapColorModel struct {
+	CardColor   string  `json:"cardColor"`
+	ColorScale  string  `json:"colorScale"`
+	ColorScheme string  `json:"colorScheme"`
+	Exponent    float64 `json:"exponent"`
+	Mode        string  `json:"mode"`
+}
+
+type heatmapTooltipModel struct {
+	Show          bool `json:"show"`
+	ShowHistogram bool `json:"showHistogram"`
+}
+
+type heatmapYAxisModel struct {
+	Decimals    *int     `json:"decimals"`
+	Format      string   `json:"format"`
+	LogBase     int      `json:"logBase"`
+	Max         *float64 `json:"max"`
+	Min         *float64 `json:"min"`
+	Show        bool     `json:"show"`
+	SplitFactor *float64 `json:"splitFactor"`
+}
+
+type showModel struct {
+	Show bool `json:"show"`
+}
+
+func newHeatmapModel(h Heatmap) heatmapModel {
+	targets := []targetModel{{Expr: h.Query, Format: "heatmap", IntervalFactor: 1, LegendFormat: h.Legend, RefID: "A"}}
+	return heatmapModel{
+		panelModel: newPanelModel(PanelTypeHeatmap, h.Datasource, h.Description, h.Title, h.Height, h.Width, h.PosX, h.PosY, targets),
+		Color: heatmapColorModel{
+			CardColor:   "#b4ff00",
+			ColorScale:  "sqrt",
+			ColorScheme: "interpolateOranges",
+			Exponent:    0.5,
+			Mode:        "spectrum",
+		},
+		DataFormat:      "tsbuckets",
+		HideZeroBuckets: true,
+		HighlightCards:  true,
+		Tooltip:         heatmapTooltipModel{Show: true, ShowHistogram: true},
+		XAxis:           showModel{Show: true},
+		YAxis:           heatmapYAxisModel{Format: h.Format, LogBase: 1, Show: true},
+		YBucketBound:    "upper",
+	}
+}
+
+type singlestatModel struct {
+	panelModel
+	CacheTimeout    *string                   `json:"cacheTimeout"`
+	ColorBackground bool                      `json:"colorBackground"`
+	ColorValue      bool                      `json:"colorValue"`
+	Colors          []string                  `json:"colors"`
+	Format          string                    `json:"format"`
+	Gauge           singlestatGaugeModel      `json:"gauge"`
+	Interval        *string                   `json:"interval"`
+	MappingType     int                       `json:"mappingType"`
+	MappingTypes    []singlestatMappingType   `json:"mappingTypes"`
+	MaxDataPoints   int                       `json:"maxDataPoints"`
+	NullPointMode   string                    `json:"nullPointMode"`
+	NullText        *string                   `json:"nullText"`
+	Options         struct{}                  `json:"options"`
+	Postfix         string                    `json:"postfix"`
+	PostfixFontSize string                    `json:"postfixFontSize"`
+	Prefix          string                    `json:"prefix"`
+	PrefixFontSize  string                    `json:"prefixFontSize"`
+	RangeMaps       []singlestatRangeMapModel `json:"rangeMaps"`
+	Sparkline       singlestatSparklineModel  `json:"sparkline"`
+	TableColumn     string                    `json:"tableColumn"`
+	Thresholds      string                    `json:"thresholds"`
+	TimeFrom        *string                   `json:"timeFrom"`
+	TimeShift       *string                   `json:"timeShift"`
+	ValueFontSize   string                    `json:"valueFontSize"`
+	ValueMaps       []singlestatValueMapModel `json:"valueMaps"`
+	ValueName       string                    `json:"valueName"`
+}
+
+type singlestatGaugeModel struct {
+	MaxValue         int  `json:"maxValue"`
+	MinValue         int  `json:"minValue"`
+	Show             bool `json:"show"`
+	ThresholdLabels  bool `json:"thresholdLabels"`
+	ThresholdMarkers bool `json:"thresholdMarkers"`
+}
+
+type singlestatMappingType struct {
+	Name  string `json:"name"`
+	Value int    `json:"value"`
+}
+
+type singlestatRangeMapModel struct {
+	From string `json:"from"`
+	Text string `json:"text"`
+	To   string `json:"to"`
+}
+
+type singlestatSparklineModel struct {
+	FillColor string `json:"fillColor"`
+	Full      bool   `json:"full"`
+	LineColor string `json:"lineColor"`
+	Show      bool   `json:"show"`
+}
+
+type singlestatValueMapModel struct {
+	Op    string `json:"op"`
+	Text  string `json:"text"`
+	Value string `json:"value"`
+}
+
+func newSinglestatModel(s Singlestat) singlestatModel {
+	m := singlestatModel{
+		panelModel:  newPanelModel(PanelTypeSinglestat, s.Datasource, s.Description, s.Title, s.Height, s.Width, s.PosX, s.PosY, newInstantTargetModel(s.Query, s.Legend, "time_series")),
+		ColorValue:  true,
+		Colors:      []string{},
+		Format:      s.Format,
+		Gauge:       singlestatGaugeModel{MaxValue: 100, Show: false, ThresholdMarkers: true},
+		MappingType: 1,
+		MappingTypes: []singlestatMappingType{
+			{Name: "value to text", Value: 1},
+			{Name: "range to text", Value: 2},
+		},
+		MaxDataPoints:   100,
+		NullPointMode:   "connected",
+		PostfixFontSize: "50%",
+		PrefixFontSize:  "50%",
+		RangeMaps:       []singlestatRangeMapModel{{From: "null", Text: "N/A", To: "null"}},
+		Sparkline: singlestatSparklineModel{
+			FillColor: "rgba(31, 118, 189, 0.18)",
+			LineColor: "rgb(31, 120, 193)",
+		},
+		Thresholds:    s.ThresholdValue + "," + s.ThresholdValue,
+		ValueFontSize: "80%",
+		ValueMaps:     []singlestatValueMapModel{{Op: "=", Text: "N/A", Value: "null"}},
+		ValueName:     s.ValueName,
+	}
+	if s.ThresholdInvertYes {
+		m.Colors = append(m.Colors, "#d44a3a", "rgba(237, 129, 40, 0.89)", "#299c46")
+	}
+
+	if s.ThresholdInvertNo {
+		m.Colors = append(m.Colors, "#299c46", "rgba(237, 129, 40, 0.89)", "#d44a3a")
+	}
+
+	return m
+}
+
+type fieldConfigModel struct {
+	Defaults  fieldDefaultsModel   `json:"defaults"`
+	Overrides []fieldOverrideModel `json:"overrides"`
+}
+
+type fieldDefaultsModel struct {
+	Custom     interface{}         `json:"custom,omitempty"`
+	Mappings   []valueMappingModel `json:"mappings"`
+	Max        *json.Number        `json:"max,omitempty"`
+	Min        *json.Number        `json:"min,omitempty"`
+	Thresholds thresholdsModel     `json:"thresholds"`
+	Unit       string              `json:"unit"`
+}
+
+type valueMappingModel struct {
+	Options map[string]valueMappingTextModel `json:"options"`
+	Type    string                           `json:"type"`
+}
+
+type valueMappingTextModel struct {
+	Text string `json:"text"`
+}
+
+type thresholdsModel struct {
+	Mode  string               `json:"mode"`
+	Steps []thresholdStepModel `json:"steps"`
+}
+
+type thresholdStepModel struct {
+	Color string       `json:"color"`
+	Value *json.Number `json:"value"`
+}
+
+type fieldOverrideModel struct {
+	Matcher    fieldMatcherModel    `json:"matcher"`
+	Properties []fieldPropertyModel `json:"properties"`
+}
+
+type fieldMatcherModel struct {
+	ID      string `json:"id"`
+	Options string `json:"options"`
+}
+
+type fieldPropertyModel struct {
+	ID    string `json:"id"`
+	Value string `json:"value"`
+}
+
+// newFieldConfigModel expects a FieldConfig prepared by prepareFieldConfig.
+func newFieldConfigModel(fc FieldConfig, custom interface{}) fieldConfigModel {
+	m := fieldConfigModel{
+		Defaults: fieldDefaultsModel{
+			Custom:     custom,
+			Mappings:   []valueMappingModel{},
+			Thresholds: thresholdsModel{Mode: "absolute", Steps: []thresholdStepModel{}},
+			Unit:       fc.Format,
+		},
+		Overrides: []fieldOverrideModel{},
+	}
+	if fc.HasMappings {
+		vm := valueMappingModel{Options: map[string]valueMappingTextModel{}, Type: "value"}
+		for _, mapping := range fc.Mappings {
+			vm.Options[mapping.Value] = valueMappingTextModel{Text: mapping.Text}
+		}
+
+		m.Defaults.Mappings = append(m.Defaults.Mappings, vm)
+	}
+
+	if fc.HasMax {
+		max := json.Number(fc.Max)
+		m.Defaults.Max = &max
+	}
+
+	if fc.HasMin {
+		min := json.Number(fc.Min)
+		m.Defaults.Min = &min
+	}
+
+	for _, s := range fc.Thresholds {
+		step := thresholdStepModel{Color: s.Color}
+		if !s.Base {
+			value := json.Number(s.Value)
+			step.Value = &value
+		}
+
+		m.Defaults.Thresholds.Steps = append(m.Defaults.Thresholds.Steps, step)
+	}
+
+	return m
+}
+
+type reduceOptionsModel struct {
+	Calcs  []string `json:"calcs"`
+	Fields string   `json:"fields"`
+	Values bool     `json:"values"`
+}
+
+func newReduceOptionsModel(reduce string) reduceOptionsModel {
+	return reduceOptionsModel{Calcs: []string{reduce}}
+}
+
+type timeseriesModel struct {
+	panelModel
+	FieldConfig fieldConfigModel       `json:"fieldConfig"`
+	Options     timeseriesOptionsModel `json:"options"`
+}
+
+type timeseriesCustomModel struct {
+	DrawStyle       string    `json:"drawStyle"`
+	FillOpacity     int       `json:"fillOpacity"`
+	LineWidth       int       `json:"lineWidth"`
+	ShowPoints      string    `json:"showPoints"`
+	SpanNulls       bool      `json:"spanNulls"`
+	ThresholdsStyle modeModel `json:"thresholdsStyle"`
+}
+
+type modeModel struct {
+	Mode string `json:"mode"`
+}
+
+type timeseriesOptionsModel struct {
+	Legend  timeseriesLegendModel  `json:"legend"`
+	Tooltip timeseriesTooltipModel `json:"tooltip"`
+}
+
+type timeseriesLegendModel struct {
+	Calcs       []string `json:"calcs"`
+	DisplayMode string   `json:"displayMode"`
+	Placement   string   `json:"placement"`
+	ShowLegend  bool     `json:"showLegend"`
+}
+
+type timeseriesTooltipModel struct {
+	Mode string `json:"mode"`
+	Sort string `json:"sort"`
+}
+
+func newTimeseriesModel(t Timeseries) timeseriesModel {
+	custom := timeseriesCustomModel{
+		DrawStyle:       "line",
+		FillOpacity:     10,
+		LineWidth:       1,
+		ShowPoints:      "never",
+		ThresholdsStyle: modeModel{Mode: "off"},
+	}
+	if t.HasThresholds {
+		custom.ThresholdsStyle.Mode = "line+area"
+	}
+
+	m := timeseriesModel{
+		panelModel:  newPanelModel(PanelTypeTimeseries, t.Datasource, t.Description, t.Title, t.Height, t.Width, t.PosX, t.PosY, newTargetModels(t.Queries)),
+		FieldConfig: newFieldConfigModel(t.FieldConfig, custom),
+		Options: timeseriesOptionsModel{
+			Legend:  timeseriesLegendModel{Calcs: []string{}, DisplayMode: "hidden", Placement: "bottom"},
+			Tooltip: timeseriesTooltipModel{Mode: "multi", Sort: "desc"},
+		},
+	}
+	if t.HasLegend {
+		m.Options.Legend = timeseriesLegendModel{Calcs: []string{"mean", "max"}, DisplayMode: "table", Placement: "bottom", ShowLegend: true}
+	}
+
+	for _, o := range t.SeriesOverrides {
+		m.FieldConfig.Overrides = append(m.FieldConfig.Overrides, fieldOverrideModel{
+			Matcher:    fieldMatcherModel{ID: o.Matcher, Options: o.Alias},
+			Properties: []fieldPropertyModel{{ID: "custom.axisPlacement", Value: o.Placement}},
+		})
+	}
+
+	return m
+}
+
+type statModel struct {
+	panelModel
+	FieldConfig fieldConfigModel `json:"fieldConfig"`
+	Options     statOptionsModel `json:"options"`
+}
+
+type statOptionsModel struct {
+	ColorMode     string             `json:"colorMode"`
+	GraphMode     string             `json:"graphMode"`
+	JustifyMode   string             `json:"justifyMode"`
+	Orientation   string             `json:"orientation"`
+	ReduceOptions reduceOptionsModel `json:"reduceOptions"`
+	TextMode      string             `json:"textMode"`
+}
+
+func newStatModel(s Stat) statModel {
+	return statModel{
+		panelModel:  newPanelModel(PanelTypeStat, s.Datasource, s.Description, s.Title, s.Height, s.Width, s.PosX, s.PosY, newInstantTargetModel(s.Query, s.Legend, "time_series")),
+		FieldConfig: newFieldConfigModel(s.FieldConfig, nil),
+		Options: statOptionsModel{
+			ColorMode:     "value",
+			GraphMode:     "none",
+			JustifyMode:   "auto",
+			Orientation:   "auto",
+			ReduceOptions: newReduceOptionsModel(s.Reduce),
+			TextMode:      "auto",
+		},
+	}
+}
+
+type gaugeModel struct {
+	panelModel
+	FieldConfig fieldConfigModel  `json:"fieldConfig"`
+	Options     gaugeOptionsModel `json:"options"`
+}
+
+type gaugeOptionsModel struct {
+	Orientation          string             `json:"orientation"`
+	ReduceOptions        reduceOptionsModel `json:"reduceOptions"`
+	ShowThresholdLabels  bool               `json:"showThresholdLabels"`
+	ShowThresholdMarkers bool               `json:"showThresholdMarkers"`
+}
+
+func newGaugeModel(g Gauge) gaugeModel {
+	return gaugeModel{
+		panelModel:  newPanelModel(PanelTypeGauge, g.Datasource, g.Description, g.Title, g.Height, g.Width, g.PosX, g.PosY, newInstantTargetModel(g.Query, g.Legend, "time_series")),
+		FieldConfig: newFieldConfigModel(g.FieldConfig, nil),
+		Options: gaugeOptionsModel{
+			Orientation:          "auto",
+			ReduceOptions:        newReduceOptionsModel(g.Reduce),
+			ShowThresholdMarkers: true,
+		},
+	}
+}
+
+type barGaugeModel struct {
+	panelModel
+	FieldConfig fieldConfigModel     `json:"fieldConfig"`
+	Options     barGaugeOptionsModel `json:"options"`
+}
+
+type barGaugeOptionsModel struct {
+	DisplayMode   string             `json:"displayMode"`
+	Orientation   string             `json:"orientation"`
+	ReduceOptions reduceOptionsModel `json:"reduceOptions"`
+	ShowUnfilled  bool               `json:"showUnfilled"`
+}
+
+func newBarGaugeModel(b BarGauge) barGaugeModel {
+	return barGaugeModel{
+		panelModel:  newPanelModel(PanelTypeBarGauge, b.Datasource, b.Description, b.Title, b.Height, b.Width, b.PosX, b.PosY, newInstantTargetModel(b.Query, b.Legend, "time_series")),
+		FieldConfig: newFieldConfigModel(b.FieldConfig, nil),
+		Options: barGaugeOptionsModel{
+			DisplayMode:   "gradient",
+			Orientation:   "horizontal",
+			ReduceOptions: newReduceOptionsModel(b.Reduce),
+			ShowUnfilled:  true,
+		},
+	}
+}
+
+type tableModel struct {
+	panelModel
+	FieldConfig     fieldConfigModel      `json:"fieldConfig"`
+	Options         tableOptionsModel     `json:"options"`
+	Transformations []transformationModel `json:"transformations"`
+}
+
+type tableCustomModel struct {
+	Align       string `json:"align"`
+	DisplayMode string `json:"displayMode"`
+}
+
+type tableOptionsModel struct {
+	ShowHeader bool `json:"showHeader"`
+}
+
+type transformationModel struct {
+	ID      string                 `json:"id"`
+	Options map[string]interface{} `json:"options"`
+}
+
+func newTableModel(t Table) tableModel {
+	return tableModel{
+		panelModel:  newPanelModel(PanelTypeTable, t.Datasource, t.Description, t.Title, t.Height, t.Width, t.PosX, t.PosY, newInstantTargetModel(t.Query, t.Legend, "table")),
+		FieldConfig: newFieldConfigModel(t.FieldConfig, tableCustomModel{Align: "auto", DisplayMode: "auto"}),
+		Options:     tableOptionsModel{ShowHeader: true},
+		Transformations: []transformationModel{{
+			ID:      "organize",
+			Options: map[string]interface{}{"excludeByName": map[string]bool{"Time": true}},
+		}},
+	}
+}
@@ -0,0 +1,272 @@
+package v1
+
+import (
+	"strings"
+)
+
+var (
+	PanelTypeBarGauge   = "bargauge"
+	PanelTypeGauge      = "gauge"
+	PanelTypeStat       = "stat"
+	PanelTypeTable      = "table"
+	PanelTypeTimeseries = "timeseries"
+)
+
+// FieldConfig sets how a Timeseries, Stat, Gauge, BarGauge or Table displays values.
+// The Renderer sets the fields prefixed with "Has".
+type FieldConfig struct {
+	Format      string
+	HasMappings bool
+	HasMax      bool
+	HasMin      bool
+	// HasThresholds is set if more than the base step is defined.
+	HasThresholds bool
+	Mappings      []ValueMapping
+	Max           string
+	Min           string
+	// Thresholds color values that are equal to or greater than the value of a step.
+	// The first step is the base step. Its value is ignored. Values are colored green if no steps are defined.
+	Thresholds []ThresholdStep
+}
+
+// A ThresholdStep is one step of the thresholds of a FieldConfig.
+type ThresholdStep struct {
+	Base    bool
+	Color   string
+	HasMore bool
+	Value   string
+}
+
+// A ValueMapping displays Text instead of Value.
+type ValueMapping struct {
+	HasMore bool
+	Text    string
+	Value   string
+}
+
+// A Timeseries is rendered as a timeseries panel by Grafana. It replaces the graph panel.
+type Timeseries struct {
+	FieldConfig
+	Datasource      string
+	Description     string
+	HasDatasource   bool
+	HasLegend       bool
+	Height          int
+	ID              int
+	Legend          string
+	PosX            int
+	PosY            int
+	Queries         []GraphQuery
+	SeriesOverrides []TimeseriesOverride
+	Title           string
+	Width           int
+}
+
+// Type implements Panel.
+func (t Timeseries) Type() string {
+	return PanelTypeTimeseries
+}
+
+// A TimeseriesOverride places the series that match Alias on the y-axis at Placement, "left" or "right".
+// Matcher is "byRegexp" if Alias is a regular expression enclosed in "/" and "byName" otherwise.
+type TimeseriesOverride struct {
+	Alias     string
+	HasMore   bool
+	Matcher   string
+	Placement string
+}
+
+// A Stat is rendered as a stat panel by Grafana. It replaces the singlestat panel.
+// Reduce is the calculation that reduces the series to one value, e.g. "lastNotNull" or "mean".
+type Stat struct {
+	FieldConfig
+	Datasource    string
+	Description   string
+	HasDatasource bool
+	Height        int
+	ID            int
+	Legend        string
+	PosX          int
+	PosY          int
+	Query         string
+	Reduce        string
+	Title         string
+	Width         int
+}
+
+// Type implements Panel.
+func (s Stat) Type() string {
+	return PanelTypeStat
+}
+
+// A Gauge is rendered as a gauge panel by Grafana.
+// Min and Max of its FieldConfig set the range of the gauge.
+type Gauge struct {
+	FieldConfig
+	Datasource    string
+	Description   string
+	HasDatasource bool
+	Height        int
+	ID            int
+	Legend        string
+	PosX          int
+	PosY          int
+	Query         string
+	Reduce        string
+	Title         string
+	Width         int
+}
+
+// Type implements Panel.
+func (g Gauge) Type() string {
+	return PanelTypeGauge
+}
+
+// A BarGauge is rendered as a bar gauge panel by Grafana. It displays one bar per series.
+type BarGauge struct {
+	FieldConfig
+	Datasource    string
+	Description   string
+	HasDatasource bool
+	Height        int
+	ID            int
+	Legend        string
+	PosX          int
+	PosY          int
+	Query         string
+	Reduce        string
+	Title         string
+	Width         int
+}
+
+// Type implements Panel.
+func (b BarGauge) Type() string {
+	return PanelTypeBarGauge
+}
+
+// A Table is rendered as a table panel by Grafana. It displays the current value and the labels of each series.
+type Table struct {
+	FieldConfig
+	Datasource    string
+	Description   string
+	HasDatasource bool
+	Height        int
+	ID            int
+	Legend        string
+	PosX          int
+	PosY          int
+	Query         string
+	Title         string
+	Width         int
+}
+
+// Type implements Panel.
+func (t Table) Type() string {
+	return PanelTypeTable
+}
+
+// prepareFieldConfig sets the defaults of a FieldConfig.
+func prepareFieldConfig(fc FieldConfig) FieldConfig {
+	if len(fc.Thresholds) == 0 {
+		fc.Thresholds = []ThresholdStep{{Color: "green"}}
+	}
+
+	steps := make([]ThresholdStep, len(fc.Thresholds))
+	for i, s := range fc.Thresholds {
+		s.Base = i == 0
+		s.HasMore = i+1 < len(fc.Thresholds)
+		steps[i] = s
+	}
+
+	fc.Thresholds = steps
+	mappings := make([]ValueMapping, len(fc.Mappings))
+	for i, m := range fc.Mappings {
+		m.HasMore = i+1 < len(fc.Mappings)
+		mappings[i] = m
+	}
+
+	fc.Mappings = mappings
+	fc.HasMappings = len(fc.Mappings) > 0
+	fc.HasMax = fc.Max != ""
+	fc.HasMin = fc.Min != ""
+	fc.HasThresholds = len(fc.Thresholds) > 1
+	return fc
+}
+
+// graphToTimeseries converts a Graph to a Timeseries.
+// A threshold of the Graph colors the area above ("gt") or below ("lt") of it red.
+func graphToTimeseries(g Graph) Timeseries {
+	ts := Timeseries{
+		Datasource:  g.Datasource,
+		Description: g.Description,
+		HasLegend:   g.HasLegend,
+		ID:          g.ID,
+		Legend:      g.Legend,
+		Queries:     g.Queries,
+		Title:       g.Title,
+	}
+	ts.Format = g.Format
+	if g.HasThreshold {
+		switch g.ThresholdOP {
+		case "gt":
+			ts.Thresholds = []ThresholdStep{{Color: "transparent"}, {Color: "red", Value: g.ThresholdValue}}
+		case "lt":
+			ts.Thresholds = []ThresholdStep{{Color: "red"}, {Color: "transparent", Value: g.ThresholdValue}}
+		}
+	}
+
+	for _, so := range g.SeriesOverrides {
+		o := TimeseriesOverride{Alias: so.Alias, Matcher: "byName", Placement: "left"}
+		if len(so.Alias) > 1 && strings.HasPrefix(so.Alias, "/") && strings.HasSuffix(so.Alias, "/") {
+			o.Matcher = "byRegexp"
+		}
+
+		if so.YAxis == 2 {
+			o.Placement = "right"
+		}
+
+		ts.SeriesOverrides = append(ts.SeriesOverrides, o)
+	}
+
+	return ts
+}
+
+// singlestatToStat converts a Singlestat to a Stat.
+func singlestatToStat(s Singlestat) Stat {
+	st := Stat{
+		Datasource:  s.Datasource,
+		Description: s.Description,
+		ID:          s.ID,
+		Legend:      s.Legend,
+		Query:       s.Query,
+		Reduce:      reduceCalc(s.ValueName),
+		Title:       s.Title,
+	}
+	st.Format = s.Format
+	if s.ThresholdValue != "" {
+		switch {
+		case s.ThresholdInvertNo:
+			st.Thresholds = []ThresholdStep{{Color: "green"}, {Color: "red", Value: s.ThresholdValue}}
+		case s.ThresholdInvertYes:
+			st.Thresholds = []ThresholdStep{{Color: "red"}, {Color: "green", Value: s.ThresholdValue}}
+		}
+	}
+
+	return st
+}
+
+// reduceCalc returns the calculation of a stat panel that corresponds to the value name of a singlestat panel.
+func reduceCalc(valueName string) string {
+	switch valueName {
+	case "avg":
+		return "mean"
+	case "first":
+		return "firstNotNull"
+	case "total":
+		return "sum"
+	case "delta", "diff", "max", "min", "range":
+		return valueName
+	default:
+		return "lastNotNull"
+	}
+}
@@ -0,0 +1,96 @@
+package v1
+
+import (
+	"fmt"
+	"sort"
+)
+
+// MultiSource merges the Metrics read from several MetricSources, e.g. the endpoint of a service and the endpoint of
+// its sidecar.
+type MultiSource struct {
+	// RowPerSource puts the Metrics of each source in their own row instead of merging them.
+	// The row is titled with the name of the source if the source implements fmt.Stringer.
+	// It has no effect if there is only one source.
+	RowPerSource bool
+	Sources      []MetricSource
+}
+
+// Metrics implements MetricSource.
+// A Metric read from more than one source is returned once. Its label keys are the union of the label keys read from
+// all sources. Its number of series is the sum of the series read from all sources.
+func (ms *MultiSource) Metrics() ([]Metric, error) {
+	result := []Metric{}
+	index := map[string]int{}
+	rowPerSource := ms.RowPerSource && len(ms.Sources) > 1
+	for i, s := range ms.Sources {
+		metrics, err := s.Metrics()
+		if err != nil {
+			return nil, err
+		}
+
+		name := sourceName(s, i)
+		for _, m := range metrics {
+			key := m.Name
+			if rowPerSource {
+				key = name + "/" + m.Name
+				m.Group = name
+			}
+
+			pos, exists := index[key]
+			if !exists {
+				index[key] = len(result)
+				result = append(result, m)
+				continue
+			}
+
+			result[pos].LabelKeys = mergeLabelKeys(result[pos].LabelKeys, m.LabelKeys)
+			result[pos].LabelValues = mergeLabelValues(result[pos].LabelValues, m.LabelValues)
+			result[pos].Series = result[pos].Series + m.Series
+		}
+	}
+
+	return result, nil
+}
+
+func sourceName(s MetricSource, i int) string {
+	if stringer, ok := s.(fmt.Stringer); ok {
+		return stringer.String()
+	}
+
+	return fmt.Sprintf("Source %d", i+1)
+}
+
+func mergeLabelKeys(a, b []string) []string {
+	seen := map[string]struct{}{}
+	merged := []string{}
+	for _, lk := range append(append([]string{}, a...), b...) {
+		if _, exists := seen[lk]; exists {
+			continue
+		}
+
+		seen[lk] = struct{}{}
+		merged = append(merged, lk)
+	}
+
+	sort.Strings(merged)
+	return merged
+}
+
+// mergeLabelValues returns the highest number of values of each label.
+// The sources may expose the same values, so adding them up would overestimate the cardinality of a label.
+func mergeLabelValues(a, b map[string]int) map[string]int {
+	if a == nil && b == nil {
+		return nil
+	}
+
+	merged := map[string]int{}
+	for _, values := range []map[string]int{a, b} {
+		for lk, n := range values {
+			if n > merged[lk] {
+				merged[lk] = n
+			}
+		}
+	}
+
+	return merged
+}
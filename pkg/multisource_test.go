@@ -0,0 +1,53 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/prometheus/prometheus/pkg/textparse"
+	"github.com/stretchr/testify/require"
+)
+
+type staticSource []Metric
+
+func (s staticSource) Metrics() ([]Metric, error) {
+	return s, nil
+}
+
+func TestMultiSource_Metrics(t *testing.T) {
+	app := staticSource{
+		{LabelKeys: []string{"handler"}, Name: "http_requests_total", Type: textparse.MetricTypeCounter},
+		{Name: "go_goroutines", Type: textparse.MetricTypeGauge},
+	}
+	sidecar := staticSource{
+		{LabelKeys: []string{"code", "handler"}, Name: "http_requests_total", Type: textparse.MetricTypeCounter},
+		{Name: "envoy_server_live", Type: textparse.MetricTypeGauge},
+	}
+
+	ms := &MultiSource{Sources: []MetricSource{app, sidecar}}
+	metrics, err := ms.Metrics()
+	require.NoError(t, err)
+	require.Equal(t, []Metric{
+		{LabelKeys: []string{"code", "handler"}, Name: "http_requests_total", Type: textparse.MetricTypeCounter},
+		{Name: "go_goroutines", Type: textparse.MetricTypeGauge},
+		{Name: "envoy_server_live", Type: textparse.MetricTypeGauge},
+	}, metrics)
+
+	ms.RowPerSource = true
+	metrics, err = ms.Metrics()
+	require.NoError(t, err)
+	groups := groupMetrics(metrics, 0)
+	require.Len(t, groups["Source 1"], 2)
+	require.Len(t, groups["Source 2"], 2)
+	require.Len(t, groups[groupNameGeneral], 0)
+}
+
+func TestMultiSource_Metrics_RowPerSourceSingleSource(t *testing.T) {
+	app := staticSource{
+		{LabelKeys: []string{"handler"}, Name: "http_requests_total", Type: textparse.MetricTypeCounter},
+	}
+
+	ms := &MultiSource{RowPerSource: true, Sources: []MetricSource{app}}
+	metrics, err := ms.Metrics()
+	require.NoError(t, err)
+	require.Equal(t, []Metric(app), metrics)
+}
@@ -0,0 +1,341 @@
+package v1
+
+import (
+	"fmt"
+	"regexp"
+	"text/template"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// A Preset replaces the generic panels of well-known metrics, e.g. the metrics of the Go runtime, with a row of
+// hand-tuned panels.
+type Preset struct {
+	// Detect is the name of a metric. The Preset is applied only if a service exposes this metric.
+	Detect string
+	// Match selects the metrics that the Preset replaces.
+	Match *regexp.Regexp
+	// Name identifies the Preset, e.g. when selecting it via a flag.
+	Name   string
+	Panels []PresetPanel
+	// Title is the title of the row that contains the panels of the Preset.
+	Title string
+}
+
+// A PresetPanel is one panel in the row of a Preset.
+// Query is a template that receives RuleTemplateData.
+type PresetPanel struct {
+	Format    string
+	Legend    string
+	PanelType string
+	// Queries are the queries of a graph that displays more than one metric. They replace Query.
+	Queries []PresetQuery
+	Query   string
+	// Requires are the names of metrics that a service needs to expose for the panel to be created.
+	Requires []string
+	Title    string
+}
+
+// A PresetQuery is one query of a PresetPanel with its own legend.
+// Query is a template that receives RuleTemplateData.
+type PresetQuery struct {
+	Legend string
+	Query  string
+	// Requires are the names of metrics that a service needs to expose for the query to be added to the panel.
+	Requires []string
+}
+
+// DefaultPresets contains a Preset for each supported exporter or runtime.
+var DefaultPresets = []Preset{
+	{
+		Name:   "go",
+		Title:  "Go runtime",
+		Detect: "go_goroutines",
+		Match:  regexp.MustCompile(`^go_`),
+		Panels: []PresetPanel{
+			{Title: "Goroutines", Query: `go_goroutines{{.Selectors}}`, Format: "short", Requires: []string{"go_goroutines"}},
+			{Title: "OS threads", Query: `go_threads{{.Selectors}}`, Format: "short", Requires: []string{"go_threads"}},
+			{Title: "GC pause duration", Query: `max by (quantile) (go_gc_duration_seconds{{.Selectors}})`, Legend: "quantile {{quantile}}", Format: "s", Requires: []string{"go_gc_duration_seconds"}},
+			{Title: "GC runs per second", Query: `rate(go_gc_duration_seconds_count{{.Selectors}}[{{.Range}}])`, Format: "short", Requires: []string{"go_gc_duration_seconds"}},
+			{Title: "Heap", Queries: []PresetQuery{
+				{Query: `go_memstats_heap_alloc_bytes{{.Selectors}}`, Legend: "alloc {{instance}}", Requires: []string{"go_memstats_heap_alloc_bytes"}},
+				{Query: `go_memstats_heap_inuse_bytes{{.Selectors}}`, Legend: "inuse {{instance}}", Requires: []string{"go_memstats_heap_inuse_bytes"}},
+				{Query: `go_memstats_heap_sys_bytes{{.Selectors}}`, Legend: "sys {{instance}}", Requires: []string{"go_memstats_heap_sys_bytes"}},
+			}, Format: "decbytes", Requires: []string{"go_memstats_heap_alloc_bytes"}},
+			{Title: "Allocation rate", Query: `rate(go_memstats_alloc_bytes_total{{.Selectors}}[{{.Range}}])`, Format: "Bps", Requires: []string{"go_memstats_alloc_bytes_total"}},
+		},
+	},
+	{
+		Name:   "process",
+		Title:  "Process",
+		Detect: "process_start_time_seconds",
+		Match:  regexp.MustCompile(`^process_`),
+		Panels: []PresetPanel{
+			{Title: "Uptime", Query: `time() - process_start_time_seconds{{.Selectors}}`, PanelType: PanelTypeSinglestat, Format: "s", Requires: []string{"process_start_time_seconds"}},
+			{Title: "CPU cores used", Query: `rate(process_cpu_seconds_total{{.Selectors}}[{{.Range}}])`, Format: "short", Requires: []string{"process_cpu_seconds_total"}},
+			{Title: "Resident memory vs. heap", Queries: []PresetQuery{
+				{Query: `process_resident_memory_bytes{{.Selectors}}`, Legend: "resident {{instance}}", Requires: []string{"process_resident_memory_bytes"}},
+				{Query: `go_memstats_heap_inuse_bytes{{.Selectors}}`, Legend: "Go heap {{instance}}", Requires: []string{"go_memstats_heap_inuse_bytes"}},
+				{Query: `jvm_memory_bytes_used{{.With "area='heap'"}}`, Legend: "JVM heap {{instance}}", Requires: []string{"jvm_memory_bytes_used"}},
+			}, Format: "decbytes", Requires: []string{"process_resident_memory_bytes"}},
+			{Title: "File descriptor saturation", Query: `process_open_fds{{.Selectors}} / process_max_fds{{.Selectors}}`, Format: "percentunit", Requires: []string{"process_open_fds", "process_max_fds"}},
+		},
+	},
+	{
+		Name:   "node",
+		Title:  "Node",
+		Detect: "node_cpu_seconds_total",
+		Match:  regexp.MustCompile(`^node_`),
+		Panels: []PresetPanel{
+			{Title: "CPU utilisation", Query: `1 - avg by (instance) (rate(node_cpu_seconds_total{{.With "mode='idle'"}}[{{.Range}}]))`, Format: "percentunit", Requires: []string{"node_cpu_seconds_total"}},
+			{Title: "Load", Queries: []PresetQuery{
+				{Query: `node_load1{{.Selectors}}`, Legend: "1m {{instance}}", Requires: []string{"node_load1"}},
+				{Query: `node_load5{{.Selectors}}`, Legend: "5m {{instance}}", Requires: []string{"node_load5"}},
+				{Query: `node_load15{{.Selectors}}`, Legend: "15m {{instance}}", Requires: []string{"node_load15"}},
+			}, Format: "short", Requires: []string{"node_load1"}},
+			{Title: "Memory utilisation", Query: `1 - node_memory_MemAvailable_bytes{{.Selectors}} / node_memory_MemTotal_bytes{{.Selectors}}`, Format: "percentunit", Requires: []string{"node_memory_MemAvailable_bytes", "node_memory_MemTotal_bytes"}},
+			{Title: "Filesystem utilisation", Query: `1 - node_filesystem_avail_bytes{{.With "fstype!='tmpfs'"}} / node_filesystem_size_bytes{{.With "fstype!='tmpfs'"}}`, Legend: "{{instance}} {{mountpoint}}", Format: "percentunit", Requires: []string{"node_filesystem_avail_bytes", "node_filesystem_size_bytes"}},
+			{Title: "Network received", Query: `rate(node_network_receive_bytes_total{{.With "device!='lo'"}}[{{.Range}}])`, Legend: "{{instance}} {{device}}", Format: "Bps", Requires: []string{"node_network_receive_bytes_total"}},
+			{Title: "Network transmitted", Query: `rate(node_network_transmit_bytes_total{{.With "device!='lo'"}}[{{.Range}}])`, Legend: "{{instance}} {{device}}", Format: "Bps", Requires: []string{"node_network_transmit_bytes_total"}},
+			{Title: "Disk I/O utilisation", Query: `rate(node_disk_io_time_seconds_total{{.Selectors}}[{{.Range}}])`, Legend: "{{instance}} {{device}}", Format: "percentunit", Requires: []string{"node_disk_io_time_seconds_total"}},
+		},
+	},
+	{
+		Name:   "redis",
+		Title:  "Redis",
+		Detect: "redis_up",
+		Match:  regexp.MustCompile(`^redis_`),
+		Panels: []PresetPanel{
+			{Title: "Up", Query: `min(redis_up{{.Selectors}})`, PanelType: PanelTypeSinglestat, Format: "short", Requires: []string{"redis_up"}},
+			{Title: "Connected clients", Query: `redis_connected_clients{{.Selectors}}`, Format: "short", Requires: []string{"redis_connected_clients"}},
+			{Title: "Memory used vs. max", Queries: []PresetQuery{
+				{Query: `redis_memory_used_bytes{{.Selectors}}`, Legend: "used {{instance}}", Requires: []string{"redis_memory_used_bytes"}},
+				{Query: `redis_memory_max_bytes{{.Selectors}} > 0`, Legend: "max {{instance}}", Requires: []string{"redis_memory_max_bytes"}},
+			}, Format: "decbytes", Requires: []string{"redis_memory_used_bytes"}},
+			{Title: "Commands per second", Query: `sum by (cmd) (rate(redis_commands_total{{.Selectors}}[{{.Range}}]))`, Legend: "{{cmd}}", Format: "short", Requires: []string{"redis_commands_total"}},
+			{Title: "Keyspace hit ratio", Query: `rate(redis_keyspace_hits_total{{.Selectors}}[{{.Range}}]) / (rate(redis_keyspace_hits_total{{.Selectors}}[{{.Range}}]) + rate(redis_keyspace_misses_total{{.Selectors}}[{{.Range}}]))`, Format: "percentunit", Requires: []string{"redis_keyspace_hits_total", "redis_keyspace_misses_total"}},
+			{Title: "Keys", Query: `sum by (db) (redis_db_keys{{.Selectors}})`, Legend: "{{db}}", Format: "short", Requires: []string{"redis_db_keys"}},
+		},
+	},
+	{
+		Name:   "jvm",
+		Title:  "JVM",
+		Detect: "jvm_memory_bytes_used",
+		Match:  regexp.MustCompile(`^jvm_`),
+		Panels: []PresetPanel{
+			{Title: "Heap used vs. max", Queries: []PresetQuery{
+				{Query: `jvm_memory_bytes_used{{.With "area='heap'"}}`, Legend: "used {{instance}}", Requires: []string{"jvm_memory_bytes_used"}},
+				{Query: `jvm_memory_bytes_max{{.With "area='heap'"}}`, Legend: "max {{instance}}", Requires: []string{"jvm_memory_bytes_max"}},
+			}, Format: "decbytes", Requires: []string{"jvm_memory_bytes_used"}},
+			{Title: "Non-heap used", Query: `jvm_memory_bytes_used{{.With "area='nonheap'"}}`, Format: "decbytes", Requires: []string{"jvm_memory_bytes_used"}},
+			{Title: "Time spent in GC", Query: `rate(jvm_gc_collection_seconds_sum{{.Selectors}}[{{.Range}}])`, Legend: "{{instance}} {{gc}}", Format: "percentunit", Requires: []string{"jvm_gc_collection_seconds"}},
+			{Title: "GC runs per second", Query: `rate(jvm_gc_collection_seconds_count{{.Selectors}}[{{.Range}}])`, Legend: "{{instance}} {{gc}}", Format: "short", Requires: []string{"jvm_gc_collection_seconds"}},
+			{Title: "Threads", Queries: []PresetQuery{
+				{Query: `jvm_threads_current{{.Selectors}}`, Legend: "current {{instance}}", Requires: []string{"jvm_threads_current"}},
+				{Query: `jvm_threads_daemon{{.Selectors}}`, Legend: "daemon {{instance}}", Requires: []string{"jvm_threads_daemon"}},
+			}, Format: "short", Requires: []string{"jvm_threads_current"}},
+			{Title: "Loaded classes", Query: `jvm_classes_loaded{{.Selectors}}`, Format: "short", Requires: []string{"jvm_classes_loaded"}},
+		},
+	},
+	{
+		Name:   "envoy",
+		Title:  "Envoy",
+		Detect: "envoy_server_live",
+		Match:  regexp.MustCompile(`^envoy_`),
+		Panels: []PresetPanel{
+			{Title: "Live", Query: `min(envoy_server_live{{.Selectors}})`, PanelType: PanelTypeSinglestat, Format: "short", Requires: []string{"envoy_server_live"}},
+			{Title: "Downstream requests per second", Query: `sum by (envoy_response_code_class) (rate(envoy_http_downstream_rq_xx{{.Selectors}}[{{.Range}}]))`, Legend: "{{envoy_response_code_class}}xx", Format: "reqps", Requires: []string{"envoy_http_downstream_rq_xx"}},
+			{Title: "Downstream active connections", Query: `sum by (envoy_http_conn_manager_prefix) (envoy_http_downstream_cx_active{{.Selectors}})`, Legend: "{{envoy_http_conn_manager_prefix}}", Format: "short", Requires: []string{"envoy_http_downstream_cx_active"}},
+			{Title: "Upstream requests per second", Query: `sum by (envoy_cluster_name) (rate(envoy_cluster_upstream_rq_total{{.Selectors}}[{{.Range}}]))`, Legend: "{{envoy_cluster_name}}", Format: "reqps", Requires: []string{"envoy_cluster_upstream_rq_total"}},
+			{Title: "Upstream request duration p99", Query: `histogram_quantile(0.99, sum by (le, envoy_cluster_name) (rate(envoy_cluster_upstream_rq_time_bucket{{.Selectors}}[{{.Range}}])))`, Legend: "{{envoy_cluster_name}}", Format: "ms", Requires: []string{"envoy_cluster_upstream_rq_time"}},
+			{Title: "Upstream active connections", Query: `sum by (envoy_cluster_name) (envoy_cluster_upstream_cx_active{{.Selectors}})`, Legend: "{{envoy_cluster_name}}", Format: "short", Requires: []string{"envoy_cluster_upstream_cx_active"}},
+		},
+	},
+}
+
+// PresetNames returns the names of all DefaultPresets.
+func PresetNames() []string {
+	names := []string{}
+	for _, p := range DefaultPresets {
+		names = append(names, p.Name)
+	}
+
+	return names
+}
+
+// SelectPresets returns the DefaultPresets with the given names, except the ones that are disabled.
+// The name "all" enables all DefaultPresets.
+func SelectPresets(enabled, disabled []string) ([]Preset, error) {
+	if containsString(enabled, "all") {
+		enabled = PresetNames()
+	}
+
+	byName := map[string]Preset{}
+	for _, p := range DefaultPresets {
+		byName[p.Name] = p
+	}
+
+	for _, n := range disabled {
+		if _, ok := byName[n]; !ok {
+			return nil, fmt.Errorf("unknown preset %s", n)
+		}
+	}
+
+	result := []Preset{}
+	for _, n := range enabled {
+		p, ok := byName[n]
+		if !ok {
+			return nil, fmt.Errorf("unknown preset %s", n)
+		}
+
+		if containsString(disabled, n) {
+			continue
+		}
+
+		result = append(result, p)
+	}
+
+	return result, nil
+}
+
+// applyPresets creates the panels of every Preset detected in metrics.
+// It returns the panels and the metrics that no Preset has replaced.
+func applyPresets(presets []Preset, metrics []Metric, o Options) ([]Panel, []Metric, error) {
+	names := map[string]struct{}{}
+	for _, m := range metrics {
+		names[m.Name] = struct{}{}
+	}
+
+	panels := []Panel{}
+	for _, p := range presets {
+		if _, ok := names[p.Detect]; !ok {
+			continue
+		}
+
+		log.Debugf("applying preset %s", p.Name)
+		presetPanels, err := p.panels(names, o)
+		if err != nil {
+			return nil, nil, fmt.Errorf("preset %s: %w", p.Name, err)
+		}
+
+		if len(presetPanels) == 0 {
+			continue
+		}
+
+		panels = append(panels, Row{Title: p.Title})
+		panels = append(panels, presetPanels...)
+		remaining := []Metric{}
+		for _, m := range metrics {
+			if !p.Match.MatchString(m.Name) {
+				remaining = append(remaining, m)
+			}
+		}
+
+		metrics = remaining
+	}
+
+	return panels, metrics, nil
+}
+
+func (p Preset) panels(names map[string]struct{}, o Options) ([]Panel, error) {
+	data := newRuleTemplateData(o)
+	panels := []Panel{}
+	for _, pp := range p.Panels {
+		if !hasAll(names, pp.Requires) {
+			continue
+		}
+
+		queries := pp.Queries
+		if len(queries) == 0 {
+			queries = []PresetQuery{{Query: pp.Query}}
+		}
+
+		graphQueries := []GraphQuery{}
+		for _, pq := range queries {
+			if !hasAll(names, pq.Requires) {
+				continue
+			}
+
+			t, err := presetTemplate(pq.Query)
+			if err != nil {
+				return nil, fmt.Errorf("parse query of panel %s: %w", pp.Title, err)
+			}
+
+			query, err := executeTemplate(t, data)
+			if err != nil {
+				return nil, fmt.Errorf("render query of panel %s: %w", pp.Title, err)
+			}
+
+			graphQueries = append(graphQueries, GraphQuery{Legend: pq.Legend, Query: query})
+		}
+
+		if len(graphQueries) == 0 {
+			continue
+		}
+
+		query := graphQueries[0].Query
+		switch pp.PanelType {
+		case PanelTypeSinglestat:
+			s := Singlestat{}
+			s.Format = pp.Format
+			s.Query = query
+			s.Title = pp.Title
+			s.ValueName = "current"
+			panels = append(panels, s)
+		default:
+			g := Graph{}
+			g.Format = pp.Format
+			g.HasLegend = pp.Legend != "" || len(pp.Queries) > 0
+			g.Legend = pp.Legend
+			if !g.HasLegend {
+				g.Legend = "{{instance}}"
+			}
+
+			g.Title = pp.Title
+			g.Queries = graphQueries
+			panels = append(panels, g)
+		}
+	}
+
+	return panels, nil
+}
+
+// presetTemplates contains the parsed queries of DefaultPresets.
+// It is filled once by init and only read afterwards.
+var presetTemplates = map[string]*template.Template{}
+
+func init() {
+	for _, p := range DefaultPresets {
+		for _, pp := range p.Panels {
+			queries := append([]PresetQuery{{Query: pp.Query}}, pp.Queries...)
+			for _, pq := range queries {
+				t, err := template.New("query").Parse(pq.Query)
+				if err != nil {
+					panic(fmt.Sprintf("parse query of panel %s of preset %s: %s", pp.Title, p.Name, err))
+				}
+
+				presetTemplates[pq.Query] = t
+			}
+		}
+	}
+}
+
+// presetTemplate returns the parsed template of a query.
+// Queries of DefaultPresets have been parsed by init. All other queries are parsed on every call.
+func presetTemplate(query string) (*template.Template, error) {
+	if t, ok := presetTemplates[query]; ok {
+		return t, nil
+	}
+
+	return template.New("query").Parse(query)
+}
+
+func hasAll(names map[string]struct{}, required []string) bool {
+	for _, r := range required {
+		if _, ok := names[r]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
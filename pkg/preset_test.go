@@ -0,0 +1,147 @@
+package v1
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/prometheus/prometheus/pkg/textparse"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultPresets_QueriesParse(t *testing.T) {
+	for _, p := range DefaultPresets {
+		for _, pp := range p.Panels {
+			require.Contains(t, presetTemplates, pp.Query, "%s: %s", p.Name, pp.Title)
+			for _, pq := range pp.Queries {
+				require.Contains(t, presetTemplates, pq.Query, "%s: %s", p.Name, pp.Title)
+			}
+		}
+	}
+}
+
+func TestApplyPresets_InvalidQuery(t *testing.T) {
+	presets := []Preset{{
+		Detect: "up",
+		Match:  regexp.MustCompile(`^up$`),
+		Name:   "invalid",
+		Panels: []PresetPanel{{Title: "Up", Query: `up{{.Selectors}`}},
+		Title:  "Invalid",
+	}}
+
+	_, _, err := applyPresets(presets, []Metric{{Name: "up", Type: textparse.MetricTypeGauge}}, Options{})
+	require.EqualError(t, err, `preset invalid: parse query of panel Up: template: query:1: bad character U+007D '}'`)
+}
+
+func TestApplyPresets(t *testing.T) {
+	metrics := []Metric{
+		{Name: "go_goroutines", Type: textparse.MetricTypeGauge},
+		{Name: "go_gc_duration_seconds", Type: textparse.MetricTypeSummary},
+		{Name: "http_requests_total", Type: textparse.MetricTypeCounter},
+		{Name: "process_open_fds", Type: textparse.MetricTypeGauge},
+	}
+
+	panels, remaining, err := applyPresets(DefaultPresets, metrics, Options{CounterChangeFunc: "rate", Labels: []string{"instance"}, TimeRange: "5m"})
+	require.NoError(t, err)
+
+	require.Equal(t, []Metric{
+		{Name: "http_requests_total", Type: textparse.MetricTypeCounter},
+		// The process preset is only applied if process_start_time_seconds is exposed.
+		{Name: "process_open_fds", Type: textparse.MetricTypeGauge},
+	}, remaining)
+	require.Len(t, panels, 4)
+	require.Equal(t, Row{Title: "Go runtime"}, panels[0])
+	require.Equal(t, "Goroutines", panels[1].(Graph).Title)
+	require.Equal(t, `go_goroutines{instance=~"$instance"}`, panels[1].(Graph).Queries[0].Query)
+	require.Equal(t, "GC pause duration", panels[2].(Graph).Title)
+	require.Equal(t, "GC runs per second", panels[3].(Graph).Title)
+	require.Equal(t, `rate(go_gc_duration_seconds_count{instance=~"$instance"}[5m])`, panels[3].(Graph).Queries[0].Query)
+}
+
+func TestApplyPresets_With(t *testing.T) {
+	metrics := []Metric{{Name: "node_cpu_seconds_total", Type: textparse.MetricTypeCounter}}
+
+	panels, remaining, err := applyPresets(DefaultPresets, metrics, Options{Labels: []string{"instance"}, TimeRange: "1m"})
+	require.NoError(t, err)
+
+	require.Empty(t, remaining)
+	require.Len(t, panels, 2)
+	require.Equal(t, `1 - avg by (instance) (rate(node_cpu_seconds_total{instance=~"$instance",mode='idle'}[1m]))`, panels[1].(Graph).Queries[0].Query)
+}
+
+func TestApplyPresets_MultipleQueries(t *testing.T) {
+	metrics := []Metric{
+		{Name: "redis_up", Type: textparse.MetricTypeGauge},
+		{Name: "redis_memory_used_bytes", Type: textparse.MetricTypeGauge},
+		{Name: "redis_memory_max_bytes", Type: textparse.MetricTypeGauge},
+	}
+
+	panels, _, err := applyPresets(DefaultPresets, metrics, Options{Labels: []string{"instance"}, TimeRange: "5m"})
+	require.NoError(t, err)
+
+	require.Len(t, panels, 3)
+	g := panels[2].(Graph)
+	require.Equal(t, "Memory used vs. max", g.Title)
+	require.True(t, g.HasLegend)
+	require.Equal(t, []GraphQuery{
+		{Legend: "used {{instance}}", Query: `redis_memory_used_bytes{instance=~"$instance"}`},
+		{Legend: "max {{instance}}", Query: `redis_memory_max_bytes{instance=~"$instance"} > 0`},
+	}, g.Queries)
+}
+
+func TestDefaultPresets_NoOr(t *testing.T) {
+	// "or" matches series by their labels without __name__ and drops the series of all but the first metric.
+	for _, p := range DefaultPresets {
+		for _, pp := range p.Panels {
+			require.NotContains(t, pp.Query, " or ", "%s: %s", p.Name, pp.Title)
+			for _, pq := range pp.Queries {
+				require.NotContains(t, pq.Query, " or ", "%s: %s", p.Name, pp.Title)
+			}
+		}
+	}
+}
+
+func TestSelectPresets(t *testing.T) {
+	presets, err := SelectPresets(PresetNames(), []string{"go", "jvm"})
+	require.NoError(t, err)
+	names := []string{}
+	for _, p := range presets {
+		names = append(names, p.Name)
+	}
+	require.Equal(t, []string{"process", "node", "redis", "envoy"}, names)
+
+	presets, err = SelectPresets([]string{"all"}, []string{"envoy"})
+	require.NoError(t, err)
+	require.Len(t, presets, 5)
+
+	presets, err = SelectPresets([]string{}, nil)
+	require.NoError(t, err)
+	require.Empty(t, presets)
+
+	_, err = SelectPresets([]string{"unknown"}, nil)
+	require.EqualError(t, err, "unknown preset unknown")
+
+	_, err = SelectPresets(PresetNames(), []string{"unknown"})
+	require.EqualError(t, err, "unknown preset unknown")
+}
+
+func TestApplyPresets_QueryRequires(t *testing.T) {
+	metrics := []Metric{
+		{Name: "go_goroutines", Type: textparse.MetricTypeGauge},
+		{Name: "go_memstats_heap_inuse_bytes", Type: textparse.MetricTypeGauge},
+		{Name: "process_resident_memory_bytes", Type: textparse.MetricTypeGauge},
+		{Name: "process_start_time_seconds", Type: textparse.MetricTypeGauge},
+	}
+
+	panels, remaining, err := applyPresets(DefaultPresets, metrics, Options{Labels: []string{"instance"}, TimeRange: "5m"})
+	require.NoError(t, err)
+
+	require.Empty(t, remaining)
+	require.Len(t, panels, 5)
+	require.Equal(t, Row{Title: "Process"}, panels[2])
+	g := panels[4].(Graph)
+	require.Equal(t, "Resident memory vs. heap", g.Title)
+	require.Equal(t, []GraphQuery{
+		{Legend: "resident {{instance}}", Query: `process_resident_memory_bytes{instance=~"$instance"}`},
+		{Legend: "Go heap {{instance}}", Query: `go_memstats_heap_inuse_bytes{instance=~"$instance"}`},
+	}, g.Queries)
+}
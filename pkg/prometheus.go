@@ -3,11 +3,13 @@ package v1
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"regexp"
 
 	promapi "github.com/prometheus/client_golang/api"
 	pav1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	log "github.com/sirupsen/logrus"
+	"github.com/wndhydrnt/autoboard/pkg/config"
 )
 
 var (
@@ -28,8 +30,12 @@ type Alert struct {
 // Prometheus encapsulates all interactions with the Prometheus API.
 type Prometheus struct {
 	DatasourceDefault string
-	Filters           []*regexp.Regexp
-	PromAPI           pav1.API
+	// DatasourceLoki is set on panels of alerts that use LogQL. DatasourceDefault is used if it is empty.
+	DatasourceLoki string
+	Filters        []*regexp.Regexp
+	// LogQL indicates that the queries of all rules are written in LogQL, e.g. because they have been read from Loki.
+	LogQL   bool
+	PromAPI RulesAPI
 }
 
 // ReadAlerts queries the Prometheus API for alert groups and turns them into Alerts.
@@ -55,8 +61,15 @@ func (p *Prometheus) ReadAlerts() ([]Alert, error) {
 				continue
 			}
 
-			datasource := settingString(ar, "datasource", p.DatasourceDefault)
-			metrics, err := ConvertAlertToPanel(ar, datasource)
+			var metrics interface{}
+			if p.LogQL || isLogQL(ar.Query) {
+				datasource := settingString(ar, "datasource", p.datasourceLoki())
+				metrics, err = ConvertLogQLAlertToPanel(ar, datasource)
+			} else {
+				datasource := settingString(ar, "datasource", p.DatasourceDefault)
+				metrics, err = ConvertAlertToPanel(ar, datasource)
+			}
+
 			if err != nil {
 				return nil, fmt.Errorf("convert query to metrics: %w", err)
 			}
@@ -77,6 +90,14 @@ func (p *Prometheus) ReadAlerts() ([]Alert, error) {
 	return alerts, nil
 }
 
+func (p *Prometheus) datasourceLoki() string {
+	if p.DatasourceLoki == "" {
+		return p.DatasourceDefault
+	}
+
+	return p.DatasourceLoki
+}
+
 func (p *Prometheus) isAllowed(name string) bool {
 	if len(p.Filters) == 0 {
 		return false
@@ -104,11 +125,20 @@ func settingString(r pav1.AlertingRule, key, def string) string {
 }
 
 // NewPrometheusAPI returns a new API client of Prometheus.
-func NewPrometheusAPI(addr string) (pav1.API, error) {
-	c, err := promapi.NewClient(promapi.Config{Address: addr})
+func NewPrometheusAPI(addr string, rt http.RoundTripper) (pav1.API, error) {
+	c, err := promapi.NewClient(promapi.Config{Address: addr, RoundTripper: rt})
 	if err != nil {
 		return nil, fmt.Errorf("create Prometheus API client: %w", err)
 	}
 
 	return pav1.NewAPI(c), nil
 }
+
+func newPrometheusAPIFromConfig(cfg config.Config) (pav1.API, error) {
+	rt, err := newRoundTripper(cfg.PrometheusHTTP, cfg.PrometheusTenant)
+	if err != nil {
+		return nil, fmt.Errorf("init Prometheus HTTP client: %w", err)
+	}
+
+	return NewPrometheusAPI(cfg.PrometheusAddress, rt)
+}
@@ -0,0 +1,216 @@
+package v1
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/prometheus/pkg/textparse"
+	log "github.com/sirupsen/logrus"
+)
+
+// Labels that contain the status code of an HTTP request.
+var redCodeLabels = []string{"code", "status", "status_code"}
+
+// Labels by which a RED row aggregates if no label has been chosen.
+var redAggregationLabels = []string{"handler", "route", "path", "method", "grpc_method"}
+
+// A REDService is a pair of metrics that describe the requests handled by a service.
+// A RED row displays the rate of requests, the ratio of errors and the duration of requests.
+type REDService struct {
+	// By is the label by which the panels aggregate. Panels aggregate over all series if it is empty.
+	By string
+	// CodeLabel is the label of Requests that contains the status code.
+	CodeLabel string
+	// Duration is a histogram of the duration of requests.
+	Duration Metric
+	// GRPC is true if CodeLabel contains gRPC status codes instead of HTTP status codes.
+	GRPC bool
+	// Requests is a counter of requests.
+	Requests Metric
+}
+
+// detectREDServices finds pairs of a counter of requests and a histogram of the duration of requests.
+// by is the label to aggregate by. If it is empty or not exposed by both metrics, one of redAggregationLabels is used.
+// It returns the services and the metrics that are not part of any service.
+func detectREDServices(metrics []Metric, by string) ([]REDService, []Metric) {
+	histograms := map[string]Metric{}
+	for _, m := range metrics {
+		if m.Type == textparse.MetricTypeHistogram {
+			histograms[m.Name] = m
+		}
+	}
+
+	services := []REDService{}
+	used := map[string]struct{}{}
+	for _, m := range metrics {
+		if m.Type != textparse.MetricTypeCounter {
+			continue
+		}
+
+		svc, ok := findREDPair(m, histograms)
+		if !ok {
+			continue
+		}
+
+		if _, taken := used[svc.Duration.Name]; taken {
+			continue
+		}
+
+		svc.By = findREDAggregation(svc, by)
+		log.Debugf("detected RED metrics %s and %s", svc.Requests.Name, svc.Duration.Name)
+		services = append(services, svc)
+		used[svc.Requests.Name] = struct{}{}
+		used[svc.Duration.Name] = struct{}{}
+	}
+
+	remaining := []Metric{}
+	for _, m := range metrics {
+		if _, ok := used[m.Name]; !ok {
+			remaining = append(remaining, m)
+		}
+	}
+
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Requests.Name < services[j].Requests.Name
+	})
+	return services, remaining
+}
+
+func findREDPair(requests Metric, histograms map[string]Metric) (REDService, bool) {
+	// The metrics of the Go gRPC middleware.
+	if strings.HasSuffix(requests.Name, "_handled_total") && containsString(requests.LabelKeys, "grpc_code") {
+		base := strings.TrimSuffix(requests.Name, "_handled_total")
+		if h, ok := histograms[base+"_handling_seconds"]; ok {
+			return REDService{CodeLabel: "grpc_code", Duration: h, GRPC: true, Requests: requests}, true
+		}
+
+		return REDService{}, false
+	}
+
+	if !strings.HasSuffix(requests.Name, "_requests_total") {
+		return REDService{}, false
+	}
+
+	codeLabel := ""
+	for _, l := range redCodeLabels {
+		if containsString(requests.LabelKeys, l) {
+			codeLabel = l
+			break
+		}
+	}
+
+	if codeLabel == "" {
+		return REDService{}, false
+	}
+
+	base := strings.TrimSuffix(requests.Name, "_requests_total")
+	for _, suffix := range []string{"_request_duration_seconds", "_requests_duration_seconds", "_duration_seconds"} {
+		if h, ok := histograms[base+suffix]; ok {
+			return REDService{CodeLabel: codeLabel, Duration: h, Requests: requests}, true
+		}
+	}
+
+	return REDService{}, false
+}
+
+func findREDAggregation(svc REDService, by string) string {
+	candidates := redAggregationLabels
+	if by != "" {
+		candidates = append([]string{by}, candidates...)
+	}
+
+	for _, l := range candidates {
+		if containsString(svc.Requests.LabelKeys, l) && containsString(svc.Duration.LabelKeys, l) {
+			return l
+		}
+	}
+
+	return ""
+}
+
+// Panels returns a row that contains the panels of the service.
+func (svc REDService) Panels(o Options) []Panel {
+	data := newRuleTemplateData(o)
+	selectors := data.Selectors
+	errorSelectors := data.With(fmt.Sprintf(`%s=~"5.."`, svc.CodeLabel))
+	if svc.GRPC {
+		errorSelectors = data.With(fmt.Sprintf(`%s!="OK"`, svc.CodeLabel))
+	}
+
+	aggregation := "sum"
+	legend := "{{instance}}"
+	hasLegend := false
+	if svc.By != "" {
+		aggregation = fmt.Sprintf("sum by (%s)", svc.By)
+		legend = fmt.Sprintf("{{%s}}", svc.By)
+		hasLegend = true
+	}
+
+	rate := Graph{}
+	rate.Description = svc.Requests.Help
+	rate.Format = "reqps"
+	rate.HasLegend = hasLegend
+	rate.Legend = legend
+	rate.Title = fmt.Sprintf("%s rate", svc.Requests.Name)
+	rate.Queries = []GraphQuery{
+		{Query: fmt.Sprintf("%s (rate(%s%s[%s]))", aggregation, svc.Requests.Name, selectors, o.TimeRange)},
+	}
+
+	errors := Graph{}
+	errors.Description = svc.Requests.Help
+	errors.Format = "percentunit"
+	errors.HasLegend = hasLegend
+	errors.Legend = legend
+	errors.Title = fmt.Sprintf("%s error ratio", svc.Requests.Name)
+	errors.Queries = []GraphQuery{
+		{Query: fmt.Sprintf("%s (rate(%s%s[%s])) / %s (rate(%s%s[%s]))", aggregation, svc.Requests.Name, errorSelectors, o.TimeRange, aggregation, svc.Requests.Name, selectors, o.TimeRange)},
+	}
+
+	panels := []Panel{Row{Title: fmt.Sprintf("RED %s", svc.Requests.Name)}, rate, errors}
+	bucketAggregation := "sum by (le)"
+	if svc.By != "" {
+		bucketAggregation = fmt.Sprintf("sum by (le, %s)", svc.By)
+	}
+
+	quantiles := o.Quantiles
+	if len(quantiles) == 0 {
+		quantiles = DefaultQuantiles
+	}
+
+	if o.CombineQuantiles {
+		combined := Graph{}
+		combined.Description = svc.Duration.Help
+		combined.Format = o.findFormat(svc.Duration)
+		combined.HasLegend = true
+		combined.Title = fmt.Sprintf("%s quantiles", svc.Duration.Name)
+		for _, q := range quantiles {
+			quantileLegend := quantileName(q)
+			if hasLegend {
+				quantileLegend = quantileLegend + " " + legend
+			}
+
+			combined.Queries = append(combined.Queries, GraphQuery{
+				Legend: quantileLegend,
+				Query:  fmt.Sprintf("histogram_quantile(%s, %s (rate(%s_bucket%s[%s])))", formatQuantile(q), bucketAggregation, svc.Duration.Name, selectors, o.TimeRange),
+			})
+		}
+
+		return append(panels, combined)
+	}
+
+	for _, q := range quantiles {
+		g := Graph{}
+		g.Description = svc.Duration.Help
+		g.Format = o.findFormat(svc.Duration)
+		g.HasLegend = hasLegend
+		g.Legend = legend
+		g.Title = fmt.Sprintf("%s %s", svc.Duration.Name, quantileName(q))
+		g.Queries = []GraphQuery{
+			{Query: fmt.Sprintf("histogram_quantile(%s, %s (rate(%s_bucket%s[%s])))", formatQuantile(q), bucketAggregation, svc.Duration.Name, selectors, o.TimeRange)},
+		}
+		panels = append(panels, g)
+	}
+
+	return panels
+}
@@ -0,0 +1,91 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/prometheus/prometheus/pkg/textparse"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectREDServices(t *testing.T) {
+	metrics := []Metric{
+		{Name: "http_requests_total", Type: textparse.MetricTypeCounter, LabelKeys: []string{"code", "handler", "method"}},
+		{Name: "http_request_duration_seconds", Type: textparse.MetricTypeHistogram, LabelKeys: []string{"handler", "le", "method"}},
+		{Name: "grpc_server_handled_total", Type: textparse.MetricTypeCounter, LabelKeys: []string{"grpc_code", "grpc_method"}},
+		{Name: "grpc_server_handling_seconds", Type: textparse.MetricTypeHistogram, LabelKeys: []string{"grpc_method", "le"}},
+		// No label that contains a status code.
+		{Name: "jobs_requests_total", Type: textparse.MetricTypeCounter, LabelKeys: []string{"queue"}},
+		{Name: "jobs_request_duration_seconds", Type: textparse.MetricTypeHistogram, LabelKeys: []string{"le"}},
+	}
+
+	services, remaining := detectREDServices(metrics, "")
+
+	require.Len(t, services, 2)
+	require.Equal(t, "grpc_server_handled_total", services[0].Requests.Name)
+	require.Equal(t, "grpc_server_handling_seconds", services[0].Duration.Name)
+	require.Equal(t, "grpc_method", services[0].By)
+	require.True(t, services[0].GRPC)
+	require.Equal(t, "http_requests_total", services[1].Requests.Name)
+	require.Equal(t, "http_request_duration_seconds", services[1].Duration.Name)
+	require.Equal(t, "handler", services[1].By)
+	require.Equal(t, "code", services[1].CodeLabel)
+	require.Equal(t, []Metric{metrics[4], metrics[5]}, remaining)
+
+	services, _ = detectREDServices(metrics, "method")
+	require.Equal(t, "method", services[1].By)
+}
+
+func TestREDService_Panels(t *testing.T) {
+	svc := REDService{
+		By:        "handler",
+		CodeLabel: "code",
+		Duration:  Metric{Name: "http_request_duration_seconds", Type: textparse.MetricTypeHistogram},
+		Requests:  Metric{Name: "http_requests_total", Type: textparse.MetricTypeCounter},
+	}
+
+	panels := svc.Panels(Options{Labels: []string{"instance"}, TimeRange: "5m"})
+
+	require.Len(t, panels, 6)
+	require.Equal(t, Row{Title: "RED http_requests_total"}, panels[0])
+	require.Equal(t, `sum by (handler) (rate(http_requests_total{instance=~"$instance"}[5m]))`, panels[1].(Graph).Queries[0].Query)
+	require.Equal(t, "{{handler}}", panels[1].(Graph).Legend)
+	require.Equal(t, `sum by (handler) (rate(http_requests_total{instance=~"$instance",code=~"5.."}[5m])) / sum by (handler) (rate(http_requests_total{instance=~"$instance"}[5m]))`, panels[2].(Graph).Queries[0].Query)
+	require.Equal(t, "http_request_duration_seconds p99", panels[5].(Graph).Title)
+	require.Equal(t, `histogram_quantile(0.99, sum by (le, handler) (rate(http_request_duration_seconds_bucket{instance=~"$instance"}[5m])))`, panels[5].(Graph).Queries[0].Query)
+	require.Equal(t, "s", panels[5].(Graph).Format)
+
+	svc = REDService{CodeLabel: "grpc_code", GRPC: true, Requests: Metric{Name: "grpc_server_handled_total"}}
+	panels = svc.Panels(Options{TimeRange: "1m"})
+	require.Equal(t, `sum (rate(grpc_server_handled_total{grpc_code!="OK"}[1m])) / sum (rate(grpc_server_handled_total[1m]))`, panels[2].(Graph).Queries[0].Query)
+	require.False(t, panels[2].(Graph).HasLegend)
+}
+
+func TestREDService_Panels_Quantiles(t *testing.T) {
+	svc := REDService{
+		By:        "handler",
+		CodeLabel: "code",
+		Duration:  Metric{Name: "http_request_duration_seconds", Type: textparse.MetricTypeHistogram},
+		Requests:  Metric{Name: "http_requests_total", Type: textparse.MetricTypeCounter},
+	}
+
+	panels := svc.Panels(Options{Quantiles: []float64{0.95, 0.999}, TimeRange: "5m"})
+	require.Len(t, panels, 5)
+	require.Equal(t, "http_request_duration_seconds p95", panels[3].(Graph).Title)
+	require.Equal(t, "http_request_duration_seconds p99.9", panels[4].(Graph).Title)
+	require.Equal(t, "histogram_quantile(0.999, sum by (le, handler) (rate(http_request_duration_seconds_bucket[5m])))", panels[4].(Graph).Queries[0].Query)
+
+	panels = svc.Panels(Options{CombineQuantiles: true, Quantiles: []float64{0.95, 0.999}, TimeRange: "5m"})
+	require.Len(t, panels, 4)
+	combined := panels[3].(Graph)
+	require.Equal(t, "http_request_duration_seconds quantiles", combined.Title)
+	require.Equal(t, []GraphQuery{
+		{Legend: "p95 {{handler}}", Query: "histogram_quantile(0.95, sum by (le, handler) (rate(http_request_duration_seconds_bucket[5m])))"},
+		{Legend: "p99.9 {{handler}}", Query: "histogram_quantile(0.999, sum by (le, handler) (rate(http_request_duration_seconds_bucket[5m])))"},
+	}, combined.Queries)
+
+	svc.By = ""
+	panels = svc.Panels(Options{CombineQuantiles: true, Quantiles: []float64{0.5}, TimeRange: "5m"})
+	require.Equal(t, []GraphQuery{
+		{Legend: "p50", Query: "histogram_quantile(0.5, sum by (le) (rate(http_request_duration_seconds_bucket[5m])))"},
+	}, panels[3].(Graph).Queries)
+}
@@ -0,0 +1,290 @@
+package v1
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/prometheus/prometheus/pkg/textparse"
+	log "github.com/sirupsen/logrus"
+	"github.com/wndhydrnt/autoboard/pkg/config"
+)
+
+// RuleTemplateData is passed to the templates of the query and the title of a panel created by a RuleConverter.
+type RuleTemplateData struct {
+	// Func is the PromQL function to apply to a counter, e.g. "rate".
+	Func string
+	// Labels are the keys of the labels of the variables of the dashboard.
+	Labels []string
+	// Name is the name of the metric.
+	Name string
+	// Range is the PromQL range duration, e.g. "5m".
+	Range string
+	// Selectors are the label selectors of the variables of the dashboard, e.g. {instance=~"$instance"}.
+	Selectors string
+}
+
+// RulePanel describes one Panel created by a RuleConverter.
+// FieldConfig only applies to the panel types timeseries, stat, gauge, bargauge and table. Its Format is ignored.
+type RulePanel struct {
+	FieldConfig FieldConfig
+	Format      string
+	Legend      string
+	PanelType   string
+	Query       *template.Template
+	Title       *template.Template
+}
+
+// RuleConverter handles metrics that match a rule configured by a user.
+// It returns the Panels described by the rule.
+type RuleConverter struct {
+	// Labels are the keys of labels that a metric needs to expose.
+	Labels []string
+	// Name matches the name of a metric. Every metric matches if it is nil.
+	Name   *regexp.Regexp
+	Panels []RulePanel
+	// Type matches the type of a metric. Every metric matches if it is empty.
+	Type textparse.MetricType
+}
+
+// NewRuleConverters creates one RuleConverter per rule configured by a user.
+func NewRuleConverters(rules []config.ConverterRule) ([]MetricConverter, error) {
+	converters := []MetricConverter{}
+	for i, r := range rules {
+		err := r.Validate()
+		if err != nil {
+			return nil, fmt.Errorf("converter rule %d: %w", i+1, err)
+		}
+
+		rc := &RuleConverter{
+			Labels: r.Labels,
+			Type:   textparse.MetricType(r.Type),
+		}
+		if r.Name != "" {
+			re, err := regexp.Compile(r.Name)
+			if err != nil {
+				return nil, fmt.Errorf("converter rule %d: compile name: %w", i+1, err)
+			}
+
+			rc.Name = re
+		}
+
+		if len(r.Panels) == 0 {
+			return nil, fmt.Errorf("converter rule %d: no panels configured", i+1)
+		}
+
+		for j, p := range r.Panels {
+			rp, err := newRulePanel(p)
+			if err != nil {
+				return nil, fmt.Errorf("converter rule %d: panel %d: %w", i+1, j+1, err)
+			}
+
+			rc.Panels = append(rc.Panels, rp)
+		}
+
+		converters = append(converters, rc)
+	}
+
+	return converters, nil
+}
+
+func newRulePanel(p config.ConverterPanel) (RulePanel, error) {
+	rp := RulePanel{Format: p.Format, Legend: p.Legend, PanelType: p.Type}
+	switch rp.PanelType {
+	case "":
+		rp.PanelType = PanelTypeGraph
+	case PanelTypeBarGauge, PanelTypeGauge, PanelTypeGraph, PanelTypeSinglestat, PanelTypeStat, PanelTypeTable, PanelTypeTimeseries:
+	default:
+		return rp, fmt.Errorf("unknown panel type %s", p.Type)
+	}
+
+	var err error
+	rp.FieldConfig, err = newRuleFieldConfig(p)
+	if err != nil {
+		return rp, err
+	}
+
+	if p.Query == "" {
+		return rp, fmt.Errorf("query is empty")
+	}
+
+	rp.Query, err = template.New("query").Parse(p.Query)
+	if err != nil {
+		return rp, fmt.Errorf("parse query: %w", err)
+	}
+
+	title := p.Title
+	if title == "" {
+		title = "{{.Name}}"
+	}
+
+	rp.Title, err = template.New("title").Parse(title)
+	if err != nil {
+		return rp, fmt.Errorf("parse title: %w", err)
+	}
+
+	return rp, nil
+}
+
+// newRuleFieldConfig creates the FieldConfig of a RulePanel. Min, Max and the values of thresholds need to be numbers.
+func newRuleFieldConfig(p config.ConverterPanel) (FieldConfig, error) {
+	fc := FieldConfig{Max: p.Max, Min: p.Min}
+	err := p.Validate()
+	if err != nil {
+		return fc, err
+	}
+
+	for _, t := range p.Thresholds {
+		fc.Thresholds = append(fc.Thresholds, ThresholdStep{Color: t.Color, Value: t.Value})
+	}
+
+	values := []string{}
+	for v := range p.Mappings {
+		values = append(values, v)
+	}
+
+	sort.Strings(values)
+	for _, v := range values {
+		fc.Mappings = append(fc.Mappings, ValueMapping{Text: p.Mappings[v], Value: v})
+	}
+
+	return fc, nil
+}
+
+// Can implements MetricConverter.
+func (rc *RuleConverter) Can(m Metric) bool {
+	if rc.Name != nil && !rc.Name.MatchString(m.Name) {
+		return false
+	}
+
+	if rc.Type != "" && rc.Type != m.Type {
+		return false
+	}
+
+	for _, l := range rc.Labels {
+		if !containsString(m.LabelKeys, l) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Do implements MetricConverter.
+// A panel is skipped if one of its templates cannot be executed.
+func (rc *RuleConverter) Do(m Metric, o Options) []Panel {
+	data := newRuleTemplateData(o)
+	data.Name = m.Name
+	panels := []Panel{}
+	for _, rp := range rc.Panels {
+		query, err := executeTemplate(rp.Query, data)
+		if err != nil {
+			log.Warnf("render query of rule for metric %s: %s", m.Name, err)
+			continue
+		}
+
+		title, err := executeTemplate(rp.Title, data)
+		if err != nil {
+			log.Warnf("render title of rule for metric %s: %s", m.Name, err)
+			continue
+		}
+
+		format := rp.Format
+		if format == "" {
+			format = o.findFormat(m)
+		}
+
+		legend := rp.Legend
+		if legend == "" {
+			legends := []string{}
+			for _, lk := range m.LabelKeys {
+				legends = append(legends, fmt.Sprintf("{{%s}}", lk))
+			}
+
+			legend = strings.Join(legends, " ")
+		}
+
+		fc := rp.FieldConfig
+		fc.Format = format
+		switch rp.PanelType {
+		case PanelTypeSinglestat:
+			s := Singlestat{}
+			s.Description = m.Help
+			s.Format = format
+			s.Query = query
+			s.Title = title
+			s.ValueName = "current"
+			panels = append(panels, s)
+		case PanelTypeStat:
+			panels = append(panels, Stat{FieldConfig: fc, Description: m.Help, Query: query, Title: title})
+		case PanelTypeGauge:
+			panels = append(panels, Gauge{FieldConfig: fc, Description: m.Help, Query: query, Title: title})
+		case PanelTypeBarGauge:
+			panels = append(panels, BarGauge{FieldConfig: fc, Description: m.Help, Legend: legend, Query: query, Title: title})
+		case PanelTypeTable:
+			panels = append(panels, Table{FieldConfig: fc, Description: m.Help, Legend: legend, Query: query, Title: title})
+		case PanelTypeTimeseries:
+			ts := Timeseries{FieldConfig: fc, Description: m.Help, HasLegend: legend != "", Legend: legend, Title: title}
+			if !ts.HasLegend {
+				ts.Legend = "{{instance}}"
+			}
+
+			ts.Queries = []GraphQuery{{Query: query}}
+			panels = append(panels, ts)
+		default:
+			g := Graph{}
+			g.Description = m.Help
+			g.Format = format
+			g.HasLegend = legend != ""
+			g.Legend = legend
+			if !g.HasLegend {
+				g.Legend = "{{instance}}"
+			}
+
+			g.Title = title
+			g.Queries = []GraphQuery{{Query: query}}
+			panels = append(panels, g)
+		}
+	}
+
+	return panels
+}
+
+func newRuleTemplateData(o Options) RuleTemplateData {
+	data := RuleTemplateData{
+		Func:   o.CounterChangeFunc,
+		Labels: o.Labels,
+		Range:  o.TimeRange,
+	}
+	data.Selectors = data.With()
+	return data
+}
+
+// With returns the label selectors of the variables of the dashboard plus additional matchers, e.g.
+// {instance=~"$instance",mode='idle'}.
+func (d RuleTemplateData) With(matchers ...string) string {
+	return addLabelMatchers("", d.Labels, matchers...)
+}
+
+func executeTemplate(t *template.Template, data RuleTemplateData) (string, error) {
+	buf := &bytes.Buffer{}
+	err := t.Execute(buf, data)
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,126 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/prometheus/prometheus/pkg/textparse"
+	"github.com/stretchr/testify/require"
+	"github.com/wndhydrnt/autoboard/pkg/config"
+)
+
+func TestRuleConverter(t *testing.T) {
+	converters, err := NewRuleConverters([]config.ConverterRule{
+		{
+			Name:   "_requests_total$",
+			Type:   "counter",
+			Labels: []string{"code"},
+			Panels: []config.ConverterPanel{
+				{
+					Title:  "{{.Name}} by code",
+					Query:  "sum by (code) ({{.Func}}({{.Name}}{{.Selectors}}[{{.Range}}]))",
+					Legend: "{{code}}",
+					Format: "reqps",
+				},
+				{
+					Type:  "singlestat",
+					Query: "sum({{.Name}}{{.Selectors}})",
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, converters, 1)
+
+	c := converters[0]
+	require.False(t, c.Can(Metric{Name: "http_requests_total", Type: textparse.MetricTypeCounter}))
+	require.False(t, c.Can(Metric{Name: "http_requests_total", Type: textparse.MetricTypeGauge, LabelKeys: []string{"code"}}))
+
+	m := Metric{Name: "http_requests_total", Type: textparse.MetricTypeCounter, LabelKeys: []string{"code", "method"}}
+	require.True(t, c.Can(m))
+
+	panels := c.Do(m, Options{CounterChangeFunc: "rate", Labels: []string{"instance"}, TimeRange: "5m"})
+	require.Len(t, panels, 2)
+
+	g := panels[0].(Graph)
+	require.Equal(t, "http_requests_total by code", g.Title)
+	require.Equal(t, `sum by (code) (rate(http_requests_total{instance=~"$instance"}[5m]))`, g.Queries[0].Query)
+	require.Equal(t, "{{code}}", g.Legend)
+	require.True(t, g.HasLegend)
+	require.Equal(t, "reqps", g.Format)
+
+	s := panels[1].(Singlestat)
+	require.Equal(t, "http_requests_total", s.Title)
+	require.Equal(t, `sum(http_requests_total{instance=~"$instance"})`, s.Query)
+}
+
+func TestRuleConverter_TriedBeforeBuiltIn(t *testing.T) {
+	converters, err := NewRuleConverters([]config.ConverterRule{
+		{Name: "^up$", Panels: []config.ConverterPanel{{Query: "{{.Name}}"}}},
+	})
+	require.NoError(t, err)
+
+	d := NewDrilldown()
+	d.RuleConverters = converters
+	groups := Groups{groupNameGeneral: {{Name: "up", Type: textparse.MetricTypeGauge}}}
+	panels := d.convertGroupsToPanels(groups, Options{})
+	require.Len(t, panels, 2)
+	require.IsType(t, Graph{}, panels[1])
+}
+
+func TestNewRuleConverters_Errors(t *testing.T) {
+	_, err := NewRuleConverters([]config.ConverterRule{{Name: "foo"}})
+	require.EqualError(t, err, "converter rule 1: no panels configured")
+
+	_, err = NewRuleConverters([]config.ConverterRule{{Type: "counters", Panels: []config.ConverterPanel{{Query: "foo"}}}})
+	require.EqualError(t, err, "converter rule 1: unknown type counters, must be one of counter, gauge, gaugehistogram, histogram, info, stateset, summary, unknown")
+
+	_, err = NewRuleConverters([]config.ConverterRule{{Panels: []config.ConverterPanel{{Type: "piechart", Query: "foo"}}}})
+	require.EqualError(t, err, "converter rule 1: panel 1: unknown panel type piechart")
+
+	_, err = NewRuleConverters([]config.ConverterRule{{Panels: []config.ConverterPanel{{Type: "gauge", Query: "foo", Max: "full"}}}})
+	require.EqualError(t, err, "converter rule 1: panel 1: min or max full is not a number")
+
+	for _, v := range []string{"Inf", "NaN", "0x1p3", "1_0", " 1"} {
+		_, err = NewRuleConverters([]config.ConverterRule{{Panels: []config.ConverterPanel{{Type: "gauge", Query: "foo", Max: v}}}})
+		require.EqualError(t, err, "converter rule 1: panel 1: min or max "+v+" is not a number")
+	}
+
+	_, err = NewRuleConverters([]config.ConverterRule{{Panels: []config.ConverterPanel{{
+		Type:       "stat",
+		Query:      "foo",
+		Thresholds: []config.ConverterThreshold{{Color: "green"}, {Color: "red", Value: "high"}},
+	}}}})
+	require.EqualError(t, err, "converter rule 1: panel 1: threshold 2: value high is not a number")
+
+	_, err = NewRuleConverters([]config.ConverterRule{{Panels: []config.ConverterPanel{{Query: "{{.Name"}}}})
+	require.Error(t, err)
+}
+
+func TestRuleConverter_FieldConfig(t *testing.T) {
+	converters, err := NewRuleConverters([]config.ConverterRule{{
+		Name: "^up$",
+		Panels: []config.ConverterPanel{{
+			Type:       "gauge",
+			Query:      "avg(up)",
+			Mappings:   map[string]string{"1": "up", "0": "down"},
+			Max:        "1",
+			Min:        "0",
+			Thresholds: []config.ConverterThreshold{{Color: "red"}, {Color: "green", Value: "1"}},
+		}},
+	}})
+	require.NoError(t, err)
+
+	panels := converters[0].Do(Metric{Name: "up", Type: textparse.MetricTypeGauge}, Options{})
+	require.Len(t, panels, 1)
+	require.Equal(t, Gauge{
+		FieldConfig: FieldConfig{
+			Format:     "short",
+			Mappings:   []ValueMapping{{Text: "down", Value: "0"}, {Text: "up", Value: "1"}},
+			Max:        "1",
+			Min:        "0",
+			Thresholds: []ThresholdStep{{Color: "red"}, {Color: "green", Value: "1"}},
+		},
+		Query: "avg(up)",
+		Title: "up",
+	}, panels[0])
+}
@@ -0,0 +1,169 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	pav1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// RulesAPIPrometheus reads rules from the "/api/v1/rules" endpoint of Prometheus.
+	// Thanos Ruler and the Prometheus-compatible endpoints of Cortex, Mimir and Loki expose the same API.
+	RulesAPIPrometheus = "prometheus"
+	// RulesAPIThanos is an alias of RulesAPIPrometheus.
+	RulesAPIThanos = "thanos"
+	// RulesAPICortex reads the rule configuration of a tenant from the "/api/v1/rules" endpoint of the Cortex ruler.
+	RulesAPICortex = "cortex"
+	// RulesAPIMimir reads the rule configuration of a tenant from the "/prometheus/config/v1/rules" endpoint of the
+	// Mimir ruler.
+	RulesAPIMimir = "mimir"
+	// RulesAPILoki reads the rule configuration of a tenant from the "/loki/api/v1/rules" endpoint of the Loki ruler.
+	// All rules read from Loki are LogQL queries.
+	RulesAPILoki = "loki"
+)
+
+var rulerConfigPaths = map[string]string{
+	RulesAPICortex: "/api/v1/rules",
+	RulesAPIMimir:  "/prometheus/config/v1/rules",
+	RulesAPILoki:   "/loki/api/v1/rules",
+}
+
+// A RulesAPI returns all rule groups known to a ruler.
+// pav1.API implements this interface.
+type RulesAPI interface {
+	Rules(ctx context.Context) (pav1.RulesResult, error)
+}
+
+// NewRulesAPI returns a RulesAPI for the given kind of ruler.
+func NewRulesAPI(kind, addr string, rt http.RoundTripper) (RulesAPI, error) {
+	switch kind {
+	case "", RulesAPIPrometheus, RulesAPIThanos:
+		return NewPrometheusAPI(addr, rt)
+	case RulesAPICortex, RulesAPIMimir, RulesAPILoki:
+		return &rulerConfigAPI{
+			address: strings.TrimSuffix(addr, "/"),
+			client:  &http.Client{Timeout: 30 * time.Second, Transport: rt},
+			path:    rulerConfigPaths[kind],
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown rules API %s", kind)
+	}
+}
+
+type rulerConfigRule struct {
+	Alert       string            `yaml:"alert"`
+	Annotations map[string]string `yaml:"annotations"`
+	Expr        string            `yaml:"expr"`
+	For         model.Duration    `yaml:"for"`
+	Labels      map[string]string `yaml:"labels"`
+	Record      string            `yaml:"record"`
+}
+
+type rulerConfigGroup struct {
+	Interval model.Duration    `yaml:"interval"`
+	Name     string            `yaml:"name"`
+	Rules    []rulerConfigRule `yaml:"rules"`
+}
+
+// rulerConfigAPI reads rule groups from the configuration API of the Cortex, Mimir or Loki ruler.
+// The API returns the groups of a tenant as YAML, keyed by namespace.
+type rulerConfigAPI struct {
+	address string
+	client  *http.Client
+	path    string
+}
+
+// Rules implements RulesAPI.
+func (r *rulerConfigAPI) Rules(ctx context.Context) (pav1.RulesResult, error) {
+	result := pav1.RulesResult{}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.address+r.path, nil)
+	if err != nil {
+		return result, err
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return result, err
+	}
+
+	defer resp.Body.Close()
+	// The ruler responds with 404 if a tenant has not configured any rules.
+	if resp.StatusCode == http.StatusNotFound {
+		return result, nil
+	}
+
+	if resp.StatusCode >= 300 {
+		return result, fmt.Errorf("ruler API returned status code %d", resp.StatusCode)
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return result, err
+	}
+
+	namespaces := map[string][]rulerConfigGroup{}
+	err = yaml.Unmarshal(b, &namespaces)
+	if err != nil {
+		return result, fmt.Errorf("decode rules: %w", err)
+	}
+
+	return convertRulerConfig(namespaces), nil
+}
+
+func convertRulerConfig(namespaces map[string][]rulerConfigGroup) pav1.RulesResult {
+	names := []string{}
+	for ns := range namespaces {
+		names = append(names, ns)
+	}
+
+	sort.Strings(names)
+	result := pav1.RulesResult{}
+	for _, ns := range names {
+		for _, g := range namespaces[ns] {
+			rg := pav1.RuleGroup{
+				File:     ns,
+				Interval: time.Duration(g.Interval).Seconds(),
+				Name:     g.Name,
+			}
+			for _, rule := range g.Rules {
+				if rule.Alert == "" {
+					rg.Rules = append(rg.Rules, pav1.RecordingRule{
+						Labels: toLabelSet(rule.Labels),
+						Name:   rule.Record,
+						Query:  rule.Expr,
+					})
+					continue
+				}
+
+				rg.Rules = append(rg.Rules, pav1.AlertingRule{
+					Annotations: toLabelSet(rule.Annotations),
+					Duration:    time.Duration(rule.For).Seconds(),
+					Labels:      toLabelSet(rule.Labels),
+					Name:        rule.Alert,
+					Query:       rule.Expr,
+				})
+			}
+
+			result.Groups = append(result.Groups, rg)
+		}
+	}
+
+	return result
+}
+
+func toLabelSet(m map[string]string) model.LabelSet {
+	ls := model.LabelSet{}
+	for k, v := range m {
+		ls[model.LabelName(k)] = model.LabelValue(v)
+	}
+
+	return ls
+}
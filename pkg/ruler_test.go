@@ -0,0 +1,179 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	pav1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"github.com/wndhydrnt/autoboard/pkg/config"
+)
+
+const rulerConfigResponse = `
+logs:
+  - name: LokiAlerts
+    rules:
+      - alert: HighErrorRate
+        expr: sum(count_over_time({app="foo"} |= "error" [5m])) > 10
+        for: 5m
+        annotations:
+          ab_title: Errors
+      - record: app:errors:rate5m
+        expr: sum(rate({app="foo"} |= "error" [5m]))
+`
+
+func TestRulerConfigAPI_Rules(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/loki/api/v1/rules" || r.Header.Get(headerTenant) != "team-a" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Write([]byte(rulerConfigResponse))
+	}))
+	defer s.Close()
+
+	rt, err := newRoundTripper(config.HTTPClientConfig{}, "team-a")
+	require.NoError(t, err)
+	api, err := NewRulesAPI(RulesAPILoki, s.URL, rt)
+	require.NoError(t, err)
+	result, err := api.Rules(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, result.Groups, 1)
+	require.Equal(t, "LokiAlerts", result.Groups[0].Name)
+	require.Equal(t, "logs", result.Groups[0].File)
+	require.Equal(t, pav1.Rules{
+		pav1.AlertingRule{
+			Annotations: model.LabelSet{"ab_title": "Errors"},
+			Duration:    300,
+			Labels:      model.LabelSet{},
+			Name:        "HighErrorRate",
+			Query:       `sum(count_over_time({app="foo"} |= "error" [5m])) > 10`,
+		},
+		pav1.RecordingRule{
+			Labels: model.LabelSet{},
+			Name:   "app:errors:rate5m",
+			Query:  `sum(rate({app="foo"} |= "error" [5m]))`,
+		},
+	}, result.Groups[0].Rules)
+
+	rt, err = newRoundTripper(config.HTTPClientConfig{}, "team-b")
+	require.NoError(t, err)
+	api, err = NewRulesAPI(RulesAPILoki, s.URL, rt)
+	require.NoError(t, err)
+	result, err = api.Rules(context.Background())
+	require.NoError(t, err)
+	require.Len(t, result.Groups, 0)
+}
+
+func TestConvertLogQLAlertToPanel(t *testing.T) {
+	testCases := []struct {
+		name     string
+		query    string
+		expected Graph
+	}{
+		{
+			name:  "threshold on the right",
+			query: `sum by (app) (count_over_time({app="foo"} != "debug" [5m])) >= 10`,
+			expected: Graph{
+				Datasource:     "loki",
+				Format:         defaultFormat,
+				HasThreshold:   true,
+				Queries:        []GraphQuery{{Query: `sum by (app) (count_over_time({app="foo"} != "debug" [5m]))`}},
+				ThresholdOP:    "gt",
+				ThresholdValue: "10",
+			},
+		},
+		{
+			name:  "threshold on the left",
+			query: `5 > rate({app="foo"} |~ "a>b" [1m])`,
+			expected: Graph{
+				Datasource:     "loki",
+				Format:         defaultFormat,
+				HasThreshold:   true,
+				Queries:        []GraphQuery{{Query: `rate({app="foo"} |~ "a>b" [1m])`}},
+				ThresholdOP:    "lt",
+				ThresholdValue: "5",
+			},
+		},
+		{
+			name:  "no comparison",
+			query: `absent_over_time({app="foo"}[5m])`,
+			expected: Graph{
+				Datasource: "loki",
+				Format:     defaultFormat,
+				Queries:    []GraphQuery{{Query: `absent_over_time({app="foo"}[5m])`}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := ConvertLogQLAlertToPanel(pav1.AlertingRule{Query: tc.query}, "loki")
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, p)
+		})
+	}
+}
+
+func TestIsLogQL(t *testing.T) {
+	require.True(t, isLogQL(`sum(count_over_time({app="foo"} |= "error" [5m])) > 10`))
+	require.True(t, isLogQL(`rate({app="foo"} | json [5m]) > 1`))
+	require.False(t, isLogQL(`rate(prometheus_http_requests_total{code!="200"}[5m]) > 1`))
+	require.False(t, isLogQL(`up{job="api"} != 1`))
+}
+
+func TestConvertAlertToPanel_Threshold(t *testing.T) {
+	testCases := []struct {
+		query      string
+		expectedOP string
+	}{
+		{query: `up > 1`, expectedOP: "gt"},
+		{query: `1 < up`, expectedOP: "gt"},
+		{query: `up <= 1`, expectedOP: "lt"},
+		{query: `1 >= up`, expectedOP: "lt"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			p, err := ConvertAlertToPanel(pav1.AlertingRule{Query: tc.query}, "prometheus")
+			require.NoError(t, err)
+			g := p.(Graph)
+			require.True(t, g.HasThreshold)
+			require.Equal(t, tc.expectedOP, g.ThresholdOP)
+			require.Equal(t, "1", g.ThresholdValue)
+			require.Equal(t, []GraphQuery{{Query: "up"}}, g.Queries)
+		})
+	}
+}
+
+func TestSplitComparison(t *testing.T) {
+	testCases := []struct {
+		query       string
+		expectedLHS string
+		expectedOP  string
+		expectedRHS string
+	}{
+		{query: `sum(rate({app="foo"}[5m])) > 1`, expectedLHS: `sum(rate({app="foo"}[5m]))`, expectedOP: ">", expectedRHS: "1"},
+		{query: `sum(rate({app="foo"}[5m])) <= bool 1`, expectedLHS: `sum(rate({app="foo"}[5m]))`, expectedOP: "<=", expectedRHS: "1"},
+		{query: `sum(rate({app="foo"}[5m])) == 0`, expectedLHS: `sum(rate({app="foo"}[5m])) == 0`},
+		{query: `sum(rate({app="foo"}[5m])) != 0`, expectedLHS: `sum(rate({app="foo"}[5m])) != 0`},
+		{query: `count_over_time({app="a\\"} |= "b" [5m]) > 1`, expectedLHS: `count_over_time({app="a\\"} |= "b" [5m])`, expectedOP: ">", expectedRHS: "1"},
+		{query: `count_over_time({app='a'} |= 'x > "y' [5m]) < 1`, expectedLHS: `count_over_time({app='a'} |= 'x > "y' [5m])`, expectedOP: "<", expectedRHS: "1"},
+		{query: `rate({app="foo"} |~ "\"(" [5m]) >= 1`, expectedLHS: `rate({app="foo"} |~ "\"(" [5m])`, expectedOP: ">=", expectedRHS: "1"},
+		{query: "rate({app=\"foo\"} |~ `\\` [5m]) > 1", expectedLHS: "rate({app=\"foo\"} |~ `\\` [5m])", expectedOP: ">", expectedRHS: "1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			lhs, op, rhs := splitComparison(tc.query)
+			require.Equal(t, tc.expectedLHS, lhs)
+			require.Equal(t, tc.expectedOP, op)
+			require.Equal(t, tc.expectedRHS, rhs)
+		})
+	}
+}
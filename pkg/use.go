@@ -0,0 +1,308 @@
+package v1
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/prometheus/pkg/textparse"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// useUtilizationThreshold is the ratio of utilization above which a resource is considered busy.
+	useUtilizationThreshold = "0.8"
+
+	useRoleErrors     = "errors"
+	useRoleFree       = "free"
+	useRoleLimit      = "limit"
+	useRoleSaturation = "saturation"
+	useRoleUsed       = "used"
+)
+
+// useRoleTokens map a part of the name of a metric to the role the metric plays in describing a resource.
+// Tokens of a role are ordered by preference, e.g. "avail" is preferred over "free" if a resource exposes both.
+var useRoleTokens = []struct {
+	role   string
+	tokens []string
+}{
+	{role: useRoleUsed, tokens: []string{"used", "inuse", "usage", "open", "active", "busy", "allocated", "current", "length"}},
+	{role: useRoleFree, tokens: []string{"avail", "available", "free", "idle"}},
+	// "size" is not a token because many exporters expose the current fill level as "size", e.g. "queue_size".
+	{role: useRoleLimit, tokens: []string{"max", "limit", "capacity"}},
+	{role: useRoleSaturation, tokens: []string{"pending", "waiting", "queued", "blocked"}},
+	{role: useRoleErrors, tokens: []string{"errors", "failures", "failed", "timeout", "timeouts", "rejected", "dropped"}},
+}
+
+// A USEResource groups the metrics that describe the utilization, saturation and errors of a resource, e.g. the open
+// and the maximum number of file descriptors of a process.
+type USEResource struct {
+	// Errors are counters of errors of the resource.
+	Errors []Metric
+	// Free is a gauge of the unused part of the resource. Either Free or Used is set.
+	Free *Metric
+	// Limit is a gauge of the capacity of the resource.
+	Limit Metric
+	// Name is the name of the resource, derived from the names of its metrics.
+	Name string
+	// Saturation are gauges of the work that waits for the resource.
+	Saturation []Metric
+	// Used is a gauge of the used part of the resource. Either Free or Used is set.
+	Used *Metric
+}
+
+type useCandidate struct {
+	metric   Metric
+	priority int
+	role     string
+}
+
+// detectUSEResources finds metrics that describe the used part and the capacity of a resource, e.g.
+// "process_open_fds" and "process_max_fds".
+// It returns the resources and the metrics that are not part of any resource.
+func detectUSEResources(metrics []Metric) ([]USEResource, []Metric) {
+	candidates := map[string][]useCandidate{}
+	for _, m := range metrics {
+		key, c, ok := useRoleOf(m)
+		if !ok {
+			continue
+		}
+
+		candidates[key] = append(candidates[key], c)
+	}
+
+	resources := []USEResource{}
+	used := map[string]struct{}{}
+	for key, cs := range candidates {
+		r, ok := newUSEResource(key, cs)
+		if !ok {
+			continue
+		}
+
+		log.Debugf("detected USE metrics of resource %s", r.Name)
+		resources = append(resources, r)
+		for _, m := range r.metrics() {
+			used[m.Name] = struct{}{}
+		}
+	}
+
+	remaining := []Metric{}
+	for _, m := range metrics {
+		if _, ok := used[m.Name]; !ok {
+			remaining = append(remaining, m)
+		}
+	}
+
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].Name < resources[j].Name
+	})
+	return resources, remaining
+}
+
+// useRoleOf returns the name of the resource a metric describes and the role of the metric.
+// A gauge without any role token is a candidate for the used part of a resource, e.g. "queue" vs. "queue_capacity".
+func useRoleOf(m Metric) (string, useCandidate, bool) {
+	tokens := strings.Split(strings.Replace(m.Name, "_in_use", "_inuse", 1), "_")
+	c := useCandidate{metric: m, priority: -1, role: useRoleUsed}
+	key := []string{}
+	for _, t := range tokens {
+		role, priority := findUSERole(t)
+		if role == "" || c.priority != -1 {
+			key = append(key, t)
+			continue
+		}
+
+		c.role = role
+		c.priority = priority
+	}
+
+	switch m.Type {
+	case textparse.MetricTypeCounter:
+		if c.role != useRoleErrors {
+			return "", c, false
+		}
+
+		if len(key) > 0 && key[len(key)-1] == "total" {
+			key = key[:len(key)-1]
+		}
+	case textparse.MetricTypeGauge:
+		if c.role == useRoleErrors {
+			return "", c, false
+		}
+	default:
+		return "", c, false
+	}
+
+	if len(key) == 0 {
+		return "", c, false
+	}
+
+	if c.priority == -1 {
+		// The metric does not contain any role token. It is preferred less than any metric that does.
+		c.priority = len(useRoleTokens[0].tokens)
+	}
+
+	return strings.Join(key, "_"), c, true
+}
+
+func findUSERole(token string) (string, int) {
+	for _, rt := range useRoleTokens {
+		for i, t := range rt.tokens {
+			if t == token {
+				return rt.role, i
+			}
+		}
+	}
+
+	return "", -1
+}
+
+func newUSEResource(key string, candidates []useCandidate) (USEResource, bool) {
+	best := map[string]useCandidate{}
+	r := USEResource{Name: key}
+	for _, c := range candidates {
+		switch c.role {
+		case useRoleErrors:
+			r.Errors = append(r.Errors, c.metric)
+		case useRoleSaturation:
+			r.Saturation = append(r.Saturation, c.metric)
+		default:
+			current, exists := best[c.role]
+			if !exists || c.priority < current.priority {
+				best[c.role] = c
+			}
+		}
+	}
+
+	limit, ok := best[useRoleLimit]
+	if !ok {
+		return r, false
+	}
+
+	r.Limit = limit.metric
+	if u, ok := best[useRoleUsed]; ok {
+		r.Used = &u.metric
+	} else if f, ok := best[useRoleFree]; ok {
+		r.Free = &f.metric
+	} else {
+		return r, false
+	}
+
+	sort.Slice(r.Errors, func(i, j int) bool { return r.Errors[i].Name < r.Errors[j].Name })
+	sort.Slice(r.Saturation, func(i, j int) bool { return r.Saturation[i].Name < r.Saturation[j].Name })
+	return r, true
+}
+
+func (r USEResource) metrics() []Metric {
+	ms := []Metric{r.Limit}
+	if r.Used != nil {
+		ms = append(ms, *r.Used)
+	}
+
+	if r.Free != nil {
+		ms = append(ms, *r.Free)
+	}
+
+	ms = append(ms, r.Saturation...)
+	return append(ms, r.Errors...)
+}
+
+// Panels returns a row that contains the panels of the resource.
+// The utilization panel displays the ratio of the used part and the capacity of the resource and sets a threshold.
+func (r USEResource) Panels(o Options) []Panel {
+	selectors := labelSelectors(o.Labels)
+	part := r.Used
+	if part == nil {
+		part = r.Free
+	}
+
+	common := commonLabelKeys(part.LabelKeys, r.Limit.LabelKeys)
+	numerator := part.Name + selectors
+	// Some limits are negative if the resource is unbounded, e.g. -1 for the max of a pool of the JVM.
+	denominator := fmt.Sprintf("(%s%s > 0)", r.Limit.Name, selectors)
+	if !equalStrings(part.LabelKeys, r.Limit.LabelKeys) {
+		aggregation := "sum"
+		if len(common) > 0 {
+			aggregation = fmt.Sprintf("sum by (%s)", strings.Join(common, ", "))
+		}
+
+		numerator = fmt.Sprintf("%s (%s)", aggregation, numerator)
+		denominator = fmt.Sprintf("%s %s", aggregation, denominator)
+	}
+
+	query := fmt.Sprintf("%s / %s", numerator, denominator)
+	if r.Free != nil {
+		query = fmt.Sprintf("1 - %s", query)
+	}
+
+	utilization := Graph{}
+	utilization.Description = fmt.Sprintf("Utilization of %s calculated from %s and %s", r.Name, part.Name, r.Limit.Name)
+	utilization.Format = "percentunit"
+	utilization.HasThreshold = true
+	utilization.ThresholdOP = "gt"
+	utilization.ThresholdValue = useUtilizationThreshold
+	utilization.Title = fmt.Sprintf("%s utilization", r.Name)
+	utilization.Queries = []GraphQuery{{Query: query}}
+	utilization.HasLegend, utilization.Legend = useLegend(common)
+	panels := []Panel{Row{Title: fmt.Sprintf("USE %s", r.Name)}, utilization}
+	for _, m := range r.Saturation {
+		g := Graph{}
+		g.Description = m.Help
+		g.Format = o.findFormat(m)
+		g.HasLegend, g.Legend = useLegend(m.LabelKeys)
+		g.Title = fmt.Sprintf("%s saturation", m.Name)
+		g.Queries = []GraphQuery{{Query: m.Name + selectors}}
+		panels = append(panels, g)
+	}
+
+	for _, m := range r.Errors {
+		g := Graph{}
+		g.Description = m.Help
+		g.Format = o.findFunctionFormat(m, "rate")
+		g.HasLegend, g.Legend = useLegend(m.LabelKeys)
+		g.Title = fmt.Sprintf("%s errors", m.Name)
+		g.Queries = []GraphQuery{{Query: fmt.Sprintf("rate(%s%s[%s])", m.Name, selectors, o.TimeRange)}}
+		panels = append(panels, g)
+	}
+
+	return panels
+}
+
+func useLegend(labelKeys []string) (bool, string) {
+	if len(labelKeys) == 0 {
+		return false, "{{instance}}"
+	}
+
+	legend := []string{}
+	for _, lk := range labelKeys {
+		legend = append(legend, fmt.Sprintf("{{%s}}", lk))
+	}
+
+	return true, strings.Join(legend, " ")
+}
+
+func commonLabelKeys(a, b []string) []string {
+	common := []string{}
+	for _, l := range a {
+		if containsString(b, l) {
+			common = append(common, l)
+		}
+	}
+
+	sort.Strings(common)
+	return common
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for _, s := range a {
+		if !containsString(b, s) {
+			return false
+		}
+	}
+
+	return true
+}
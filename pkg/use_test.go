@@ -0,0 +1,77 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/prometheus/prometheus/pkg/textparse"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectUSEResources(t *testing.T) {
+	metrics := []Metric{
+		{Name: "process_open_fds", Type: textparse.MetricTypeGauge},
+		{Name: "process_max_fds", Type: textparse.MetricTypeGauge},
+		{Name: "queue_length", Type: textparse.MetricTypeGauge, LabelKeys: []string{"queue"}},
+		{Name: "queue_capacity", Type: textparse.MetricTypeGauge, LabelKeys: []string{"queue"}},
+		{Name: "queue_rejected_total", Type: textparse.MetricTypeCounter, LabelKeys: []string{"queue"}},
+		{Name: "db_pool_connections_in_use", Type: textparse.MetricTypeGauge},
+		{Name: "db_pool_connections_max", Type: textparse.MetricTypeGauge},
+		{Name: "db_pool_connections_pending", Type: textparse.MetricTypeGauge},
+		{Name: "disk_free_bytes", Type: textparse.MetricTypeGauge, LabelKeys: []string{"device"}},
+		{Name: "disk_capacity_bytes", Type: textparse.MetricTypeGauge, LabelKeys: []string{"device"}},
+		// No capacity.
+		{Name: "cache_used_bytes", Type: textparse.MetricTypeGauge},
+		{Name: "http_requests_total", Type: textparse.MetricTypeCounter},
+		// The size of a queue is its fill level, not its capacity.
+		{Name: "jobs_queue_current", Type: textparse.MetricTypeGauge},
+		{Name: "jobs_queue_size", Type: textparse.MetricTypeGauge},
+	}
+
+	resources, remaining := detectUSEResources(metrics)
+
+	require.Len(t, resources, 4)
+	require.Equal(t, "db_pool_connections", resources[0].Name)
+	require.Equal(t, "db_pool_connections_in_use", resources[0].Used.Name)
+	require.Equal(t, "db_pool_connections_max", resources[0].Limit.Name)
+	require.Equal(t, []Metric{metrics[7]}, resources[0].Saturation)
+	require.Equal(t, "disk_bytes", resources[1].Name)
+	require.Nil(t, resources[1].Used)
+	require.Equal(t, "disk_free_bytes", resources[1].Free.Name)
+	require.Equal(t, "process_fds", resources[2].Name)
+	require.Equal(t, "queue", resources[3].Name)
+	require.Equal(t, []Metric{metrics[4]}, resources[3].Errors)
+	require.Equal(t, []Metric{metrics[10], metrics[11], metrics[12], metrics[13]}, remaining)
+}
+
+func TestUSEResource_Panels(t *testing.T) {
+	used := Metric{Name: "process_open_fds", Type: textparse.MetricTypeGauge}
+	r := USEResource{Name: "process_fds", Used: &used, Limit: Metric{Name: "process_max_fds", Type: textparse.MetricTypeGauge}}
+
+	panels := r.Panels(Options{Labels: []string{"instance"}, TimeRange: "5m"})
+
+	require.Len(t, panels, 2)
+	require.Equal(t, Row{Title: "USE process_fds"}, panels[0])
+	g := panels[1].(Graph)
+	require.Equal(t, `process_open_fds{instance=~"$instance"} / (process_max_fds{instance=~"$instance"} > 0)`, g.Queries[0].Query)
+	require.Equal(t, "percentunit", g.Format)
+	require.True(t, g.HasThreshold)
+	require.Equal(t, "gt", g.ThresholdOP)
+	require.Equal(t, "0.8", g.ThresholdValue)
+}
+
+func TestUSEResource_Panels_FreeAndDifferentLabels(t *testing.T) {
+	free := Metric{Name: "pool_idle", Type: textparse.MetricTypeGauge, LabelKeys: []string{"pool", "state"}}
+	r := USEResource{
+		Name:   "pool",
+		Free:   &free,
+		Limit:  Metric{Name: "pool_max", Type: textparse.MetricTypeGauge, LabelKeys: []string{"pool"}},
+		Errors: []Metric{{Name: "pool_timeouts_total", Type: textparse.MetricTypeCounter, LabelKeys: []string{"pool"}}},
+	}
+
+	panels := r.Panels(Options{TimeRange: "5m"})
+
+	require.Len(t, panels, 3)
+	require.Equal(t, `1 - sum by (pool) (pool_idle) / sum by (pool) (pool_max > 0)`, panels[1].(Graph).Queries[0].Query)
+	require.Equal(t, "{{pool}}", panels[1].(Graph).Legend)
+	require.Equal(t, `rate(pool_timeouts_total[5m])`, panels[2].(Graph).Queries[0].Query)
+}
@@ -27,7 +27,8 @@ func TestAlert(t *testing.T) {
 	cfg.GrafanaPassword = "admin"
 	cfg.GrafanaUsername = "admin"
 	cfg.Datasource = "test_datasource"
-	err = RunAlert(cfg, []*regexp.Regexp{regexp.MustCompile(".*")}, "http://localhost:12958", "ab_")
+	cfg.PrometheusAddress = "http://localhost:12958"
+	err = RunAlert(cfg, []*regexp.Regexp{regexp.MustCompile(".*")}, "ab_")
 	require.NoError(t, err)
 
 	actual := readGrafanaDashboard("TestPanels", t)
@@ -60,8 +61,10 @@ func TestDrilldown(t *testing.T) {
 	cfg.GrafanaFolder = "Test Folder"
 	cfg.GrafanaPassword = "admin"
 	cfg.GrafanaUsername = "admin"
+	source, err := NewEndpointSource(cfg, s.URL+"/metrics")
+	require.NoError(t, err)
 	dd := NewDrilldown()
-	err = dd.Run(cfg, "rate", s.URL+"/metrics", 1, []string{"instance"}, "Drilldown Unit Test", "", "5m")
+	err = dd.Run(cfg, "rate", source, 1, []string{"instance"}, "Drilldown Unit Test", "", "5m")
 	require.NoError(t, err)
 	actual := readGrafanaDashboard("Drilldown Unit Test", t)
 	cleanVariableData(actual.Dashboard)